@@ -1,10 +1,14 @@
-package pfapi
+// handlers_projects.go
+package main
 
-// ... (other imports) ...
-import {
-"fmt" // <-- Make sure this import is present
-"github.com/GCET-Open-Source-Foundation/auth" 
-}// <-- Make sure this import is present
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
 
 // ... (getAllProjects, getPendingProjects, etc.) ...
 
@@ -30,22 +34,31 @@ func createProject(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
+	if !req.validateTaxonomy(c) {
+		return
+	}
+
+	// 4. Check roles ("admin" includes superadmins; lookups are cached)
+	isAdmin := hasRoleFor(c, creatorIDStr, "admin")
 
-	// 4. Check roles using the auth library
-	isSuperAdmin := auth.Check_permissions(creatorIDStr, SpaceSuperadmins, MemberRole)
-	isAdmin := auth.Check_permissions(creatorIDStr, SpaceAdmins, MemberRole)
+	// Planned dates; past start dates only for admins recording existing projects
+	now := today()
+	notBefore := &now
+	if isAdmin {
+		notBefore = nil
+	}
+	start, end, ok := req.plannedDates.parse(c, notBefore)
+	if !ok {
+		return
+	}
 
 	// 5. Execute logic based on role
-	if isSuperAdmin || isAdmin {
+	if isAdmin {
 		// --- AUTO-APPROVE Logic (for Superadmin/Admin) ---
-		// Insert directly into approved_projects
-		row := conn.QueryRow(context.Background(),
-			`INSERT INTO approved_projects (name, description, creator_id, creator_name, start_date, status) 
-			 VALUES ($1,$2,$3,(SELECT name FROM names WHERE id=$3), CURRENT_DATE, 'in_progress') RETURNING p_id`,
-			req.Name, req.Description, creatorID)
-
-		var pid int
-		if err := row.Scan(&pid); err != nil {
+		// Insert directly into approved_projects, starting the lifecycle as 'upcoming'
+		actorID, _ := getActorID(c)
+		pid, err := insertApprovedProject(context.Background(), req, start, end, creatorID, actorID)
+		if err != nil {
 			respondErr(c, http.StatusInternalServerError, "direct insert failed", err)
 			return
 		}
@@ -55,9 +68,9 @@ func createProject(c *gin.Context) {
 		// --- SUBMIT-TO-BUFFER Logic (for Creator/User) ---
 		// Insert into buffer_projects
 		row := conn.QueryRow(context.Background(),
-			`INSERT INTO buffer_projects (name, description, creator_id, creator_name) 
-			 VALUES ($1, $2, $3, (SELECT name FROM names WHERE id=$3)) RETURNING r_id`,
-			req.Name, req.Description, creatorID)
+			`INSERT INTO buffer_projects (name, description, creator_id, creator_name, category, tags, tech_stack, planned_start, planned_end) 
+			 VALUES ($1, $2, $3, (SELECT name FROM names WHERE id=$3), NULLIF($4, ''), $5, $6, $7, $8) RETURNING r_id`,
+			req.Name, req.Description, creatorID, req.Category, req.Tags, req.TechStack, start, end)
 
 		var rid int
 		if err := row.Scan(&rid); err != nil {
@@ -74,4 +87,27 @@ func createProject(c *gin.Context) {
 NOTE:
 You can now REMOVE the old handlers `submitProject` and `createProjectAsSuperadmin`
 from this file, as this new `createProject` function replaces them both.
-*/
\ No newline at end of file
+*/
+
+// insertApprovedProject creates an approved project for creatorID, bypassing
+// the buffer, and records its first status as set by approvedBy. Without a
+// start date it starts today.
+func insertApprovedProject(ctx context.Context, req createProjectReq, start, end *time.Time, creatorID, approvedBy int) (int, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var pid int
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO approved_projects (name, description, creator_id, creator_name, start_date, end_date, status, category, tags, tech_stack) 
+		 VALUES ($1,$2,$3,(SELECT name FROM names WHERE id=$3), COALESCE($4, `+sqlToday+`), $5, $6, NULLIF($7, ''), $8, $9) RETURNING p_id`,
+		req.Name, req.Description, creatorID, start, end, StatusUpcoming, req.Category, req.Tags, req.TechStack).Scan(&pid); err != nil {
+		return 0, err
+	}
+	if err := recordStatusChange(ctx, tx, pid, "", StatusUpcoming, approvedBy, "created by admin"); err != nil {
+		return 0, err
+	}
+	return pid, tx.Commit(ctx)
+}
@@ -1,6 +1,13 @@
 // handlers_roles.go
+package main
 
-// ... (existing assignSuperAdmin function) ...
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
 
 // POST /superadmin/roles/admin - Assign admin role
 // Only accessible by existing Superadmins.
@@ -17,17 +24,50 @@ func assignAdmin(c *gin.Context) {
 		return
 	}
 
+	// An expiry, if given, must be in the future
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	actorID, ok := getActorID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+
+	// Grants that need a second superadmin become a pending request
+	pending, err := grantNeedsConfirmation(context.Background(), SpaceAdmins, actorID)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to check grant policy", err)
+		return
+	}
+	if pending {
+		id, err := createRoleChangeRequest(context.Background(), roleChange{
+			Action: roleActionGrant, UserID: req.UserID, UserName: req.UserName,
+			Space: SpaceAdmins, GrantExpiresAt: req.ExpiresAt,
+		}, actorID)
+		if err != nil {
+			respondErr(c, http.StatusInternalServerError, "failed to file grant request", err)
+			return
+		}
+		respondRoleChangePending(c, id, "Admin grant must be confirmed by another superadmin")
+		return
+	}
+
 	// Use the helper from auth.go to update the 'names' table
 	// and call auth.Create_permissions for the 'admins' space.
-	err := AssignMemberToSpace(req.UserID, req.UserName, SpaceAdmins)
+	err = AssignMemberToSpaceUntil(req.UserID, req.UserName, SpaceAdmins, req.ExpiresAt)
 	if err != nil {
 		respondErr(c, http.StatusInternalServerError, "failed to assign admin role", err)
 		return
 	}
+	recordAudit(context.Background(), actorID, req.UserID, "role_granted", SpaceAdmins)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Admin role assigned successfully",
-		"user_id": req.UserID,
-		"space":   SpaceAdmins,
+		"message":    "Admin role assigned successfully",
+		"user_id":    req.UserID,
+		"space":      SpaceAdmins,
+		"expires_at": req.ExpiresAt,
 	})
 }
\ No newline at end of file
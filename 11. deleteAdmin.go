@@ -1,6 +1,12 @@
 // handlers_roles.go
+package main
 
-// ... (existing assignSuperAdmin and assignAdmin functions) ...
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
 
 // DELETE /superadmin/roles/admin - Revoke admin role
 // Only accessible by existing Superadmins.
@@ -11,6 +17,12 @@ func revokeAdmin(c *gin.Context) {
 		return
 	}
 
+	actorID, ok := getActorID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+
 	// Use the helper from auth.go to call auth.Delete_permission
 	// for the 'admins' space.
 	err := RemoveMemberFromSpace(req.UserID, SpaceAdmins)
@@ -18,6 +30,7 @@ func revokeAdmin(c *gin.Context) {
 		respondErr(c, http.StatusInternalServerError, "failed to revoke admin role", err)
 		return
 	}
+	recordAudit(context.Background(), actorID, req.UserID, "role_revoked", SpaceAdmins)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Admin role revoked successfully",
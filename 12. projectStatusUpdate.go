@@ -1,92 +1,198 @@
+// handlers_project_status.go
 package main
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Project lifecycle. New projects start as upcoming; completed and
+// cancelled are final.
+const (
+	StatusUpcoming   = "upcoming"
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+	StatusOnHold     = "on_hold"
+	StatusCancelled  = "cancelled"
+)
+
+// projectTransitions lists the statuses each status may move to.
+var projectTransitions = map[string][]string{
+	StatusUpcoming:   {StatusInProgress, StatusOnHold, StatusCancelled},
+	StatusInProgress: {StatusCompleted, StatusOnHold, StatusCancelled},
+	StatusOnHold:     {StatusUpcoming, StatusInProgress, StatusCancelled},
+	StatusCompleted:  {},
+	StatusCancelled:  {},
+}
+
+// errIllegalTransition is returned by changeProjectStatus for a move the
+// lifecycle does not allow.
+var errIllegalTransition = errors.New("illegal status transition")
+
 // StatusUpdateRequest is the expected JSON payload for updating a project's status
 type StatusUpdateRequest struct {
-	ProjectID int    `json:"p_id"` // The approved_projects.p_id
-	NewStatus string `json:"new_status"` // Must be 'in_progress', 'completed', or 'upcoming'
+	NewStatus string `json:"new_status" binding:"required"` // see projectTransitions
+	Reason    string `json:"reason"`
+}
+
+// StatusChange represents a record in the 'project_status_history' table.
+type StatusChange struct {
+	ID         int64     `json:"id"`
+	PID        int       `json:"p_id"`
+	FromStatus *string   `json:"from_status"` // nil when the project was created
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  *int      `json:"changed_by"` // nil for the scheduler
+	Reason     string    `json:"reason"`
+	ChangedAt  time.Time `json:"changed_at"`
 }
 
-// ... existing models
+// PUT /projects/:id/status
 // UpdateProjectStatusHandler handles the request to change the status of an approved project.
+// Only the moves in projectTransitions are allowed; each one is kept in the status history.
+// Access: SuperAdmin, Admin, Project Creator or Maintainer
 func UpdateProjectStatusHandler(c *gin.Context) {
-    // 1. Get the current user's ID and Role from the Gin Context (provided by AuthRequired middleware)
-    currentUserID, exists := c.Get("userID") // Assuming userID is stored as an interface{}
-    if !exists {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token context"})
-        return
-    }
-    
-    // Assuming the role is also stored in the context by the middleware
-    currentUserRole, exists := c.Get("userRole") 
-    if !exists {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found in token context"})
-        return
-    }
-    role := currentUserRole.(string) // Cast the role to string
-    userID := currentUserID.(int) // Cast the ID to int
-
-    // 2. Decode the request body
-    var req StatusUpdateRequest
-    if err := c.ShouldBindJSON(&req); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload", "details": err.Error()})
-        return
-    }
-    
-    // 3. Status Validation
-    validStatuses := map[string]bool{"in_progress": true, "completed": true, "upcoming": true}
-    if _, ok := validStatuses[req.NewStatus]; !ok {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status value. Must be 'in_progress', 'completed', or 'upcoming'."})
-        return
-    }
-
-    // 4. Role & Permission Check
-    
-    // Admins/Super Admins have universal rights.
-    if role == "superadmin" || role == "admin" {
-        // Admins and Superadmins are allowed to update any project's status.
-    } else if role == "creator" || role == "maintainer" {
-        // Creators and Maintainers can only update *their own* project.
-        var projectCreatorID int
-        err := db.QueryRow("SELECT creator_id FROM approved_projects WHERE p_id = $1", req.ProjectID).Scan(&projectCreatorID)
-        
-        if err == sql.ErrNoRows {
-            c.JSON(http.StatusNotFound, gin.H{"error": "Project not found."})
-            return
-        }
-        if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error checking project creator"})
-            return
-        }
-        
-        // A Creator can delete their project[cite: 26], and Maintainers have the same allowances 
-        // as the Creator except deletion[cite: 27], so both can update status.
-        if projectCreatorID != userID {
-            c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. Only the Creator/Maintainer or a higher role can update this project's status."})
-            return
-        }
-    } else {
-        // All other roles (Contributor, Viewer) are forbidden.
-        c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied. You must be a Creator, Maintainer, Admin, or Superadmin to update project status."})
-        return
-    }
-
-    // 5. Execute the Update
-    result, err := db.Exec(`
-        UPDATE approved_projects 
-        SET status = $1
-        WHERE p_id = $2
-    `, req.NewStatus, req.ProjectID)
-
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project status: " + err.Error()})
-        return
-    }
-
-    rowsAffected, _ := result.RowsAffected()
-    if rowsAffected == 0 {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Project not found or status is already the same."})
-        return
-    }
-
-    c.JSON(http.StatusOK, gin.H{"message": "Project status updated successfully to " + req.NewStatus})
-}
\ No newline at end of file
+	// 1. Get Project ID from URL
+	p_id, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	// 2. Decode the request body
+	var req StatusUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload", "details": err.Error()})
+		return
+	}
+
+	// 3. Status Validation
+	if _, ok := projectTransitions[req.NewStatus]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status value. Must be one of: " + strings.Join(projectStatuses(), ", ")})
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len(req.Reason) > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason must be at most 500 characters"})
+		return
+	}
+
+	// 4. --- Permission Check (see projectPolicy) ---
+	if !authorizeProject(c, ActionUpdateStatus, p_id) {
+		return
+	}
+	actorID, _ := getActorID(c)
+
+	// 5. Execute the Update
+	from, err := changeProjectStatus(context.Background(), p_id, req.NewStatus, actorID, req.Reason)
+	if err == errProjectNotFound {
+		respondErr(c, http.StatusNotFound, "project not found", nil)
+		return
+	}
+	if err == errIllegalTransition {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   fmt.Sprintf("cannot move a project from %s to %s", from, req.NewStatus),
+			"allowed": projectTransitions[from],
+		})
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to update project status", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project status updated successfully to " + req.NewStatus,
+		"from_status": from, "status": req.NewStatus})
+}
+
+// GET /projects/:id/status-history - Every status change of a project, oldest first
+func getProjectStatusHistory(c *gin.Context) {
+	pid, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	rows, err := conn.Query(context.Background(),
+		`SELECT id, p_id, from_status, to_status, changed_by, reason, changed_at
+         FROM project_status_history WHERE p_id=$1 ORDER BY changed_at, id`, pid)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch status history", err)
+		return
+	}
+	out, err := pgx.CollectRows(rows, pgx.RowToStructByPos[StatusChange])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"p_id": pid, "items": out})
+}
+
+// --- Helpers ---
+
+// changeProjectStatus moves approved project pid to status to, if the
+// lifecycle allows it, and records the change for changedBy (0 for the
+// scheduler). It returns the previous status, also with errIllegalTransition.
+func changeProjectStatus(ctx context.Context, pid int, to string, changedBy int, reason string) (string, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var from string
+	err = tx.QueryRow(ctx, `SELECT status FROM approved_projects WHERE p_id=$1 FOR UPDATE`, pid).Scan(&from)
+	if err == pgx.ErrNoRows {
+		return "", errProjectNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if !canTransition(from, to) {
+		return from, errIllegalTransition
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE approved_projects SET status=$2 WHERE p_id=$1`, pid, to); err != nil {
+		return from, err
+	}
+	if err := recordStatusChange(ctx, tx, pid, from, to, changedBy, reason); err != nil {
+		return from, err
+	}
+	return from, tx.Commit(ctx)
+}
+
+// recordStatusChange adds a line to the status history. from is "" when the
+// project has just been created; changedBy is 0 for the scheduler.
+func recordStatusChange(ctx context.Context, db execer, pid int, from, to string, changedBy int, reason string) error {
+	_, err := db.Exec(ctx,
+		`INSERT INTO project_status_history (p_id, from_status, to_status, changed_by, reason)
+         VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, 0), $5)`,
+		pid, from, to, changedBy, reason)
+	if err != nil {
+		return fmt.Errorf("record status change: %w", err)
+	}
+	return nil
+}
+
+// canTransition reports whether the lifecycle allows moving from one status
+// to another.
+func canTransition(from, to string) bool {
+	for _, s := range projectTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// projectStatuses returns every lifecycle status, in lifecycle order.
+func projectStatuses() []string {
+	return []string{StatusUpcoming, StatusInProgress, StatusOnHold, StatusCompleted, StatusCancelled}
+}
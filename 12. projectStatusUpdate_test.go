@@ -0,0 +1,72 @@
+// 12. projectStatusUpdate_test.go
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestProjectTransitions(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusUpcoming, StatusInProgress, true},
+		{StatusUpcoming, StatusOnHold, true},
+		{StatusUpcoming, StatusCancelled, true},
+		{StatusUpcoming, StatusCompleted, false},
+		{StatusInProgress, StatusCompleted, true},
+		{StatusInProgress, StatusOnHold, true},
+		{StatusInProgress, StatusCancelled, true},
+		{StatusInProgress, StatusUpcoming, false},
+		{StatusOnHold, StatusUpcoming, true},
+		{StatusOnHold, StatusInProgress, true},
+		{StatusOnHold, StatusCancelled, true},
+		{StatusOnHold, StatusCompleted, false},
+		{StatusCompleted, StatusInProgress, false},
+		{StatusCompleted, StatusCancelled, false},
+		{StatusCancelled, StatusUpcoming, false},
+		{StatusCancelled, StatusInProgress, false},
+		{StatusUpcoming, StatusUpcoming, false},
+		{"", StatusUpcoming, false},
+		{StatusUpcoming, "archived", false},
+	}
+	for _, tt := range tests {
+		if got := canTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("canTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+// Every status is in the map, every target is a status, and only completed
+// and cancelled are final.
+func TestProjectTransitionsComplete(t *testing.T) {
+	statuses := projectStatuses()
+	if len(projectTransitions) != len(statuses) {
+		t.Fatalf("projectTransitions has %d statuses, projectStatuses %d", len(projectTransitions), len(statuses))
+	}
+	for _, s := range statuses {
+		targets, ok := projectTransitions[s]
+		if !ok {
+			t.Errorf("status %q has no transitions entry", s)
+			continue
+		}
+		final := s == StatusCompleted || s == StatusCancelled
+		if final != (len(targets) == 0) {
+			t.Errorf("status %q: %d transitions, final %v", s, len(targets), final)
+		}
+		for _, to := range targets {
+			if !slices.Contains(statuses, to) {
+				t.Errorf("%q -> unknown status %q", s, to)
+			}
+			if to == s {
+				t.Errorf("%q may move to itself", s)
+			}
+		}
+	}
+	for _, s := range openProjectStatuses {
+		if len(projectTransitions[s]) == 0 {
+			t.Errorf("open status %q is final", s)
+		}
+	}
+}
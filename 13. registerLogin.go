@@ -0,0 +1,131 @@
+// handlers_auth.go
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/GCET-Open-Source-Foundation/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Models ---
+
+// registerReq is the JSON body for POST /auth/register.
+type registerReq struct {
+	UserID   int    `json:"user_id" binding:"required"`
+	UserName string `json:"user_name" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// loginReq is the JSON body for POST /auth/login.
+type loginReq struct {
+	UserID   int    `json:"user_id" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// --- Handlers ---
+
+// POST /auth/register - create credentials in the auth user store
+// and the matching 'names' row, then start a session.
+// IDs that already belong to someone (see userIDInUse) are refused.
+func registerUser(c *gin.Context) {
+	var req registerReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body, user_id, user_name and password (min 8 chars) are required"})
+		return
+	}
+	if req.UserID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+	if req.UserID >= oidcUserIDStart {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is reserved for single sign-on accounts"})
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to begin transaction", err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Refuse IDs that already have a profile, role grant or SSO link
+	_, inUse, err := userIDInUse(ctx, tx, req.UserID)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to check user id", err)
+		return
+	}
+	if inUse {
+		respondErr(c, http.StatusConflict, "user is already registered", nil)
+		return
+	}
+
+	// 2. Create the credentials in the auth library's user store
+	if err := auth.Register_user(uidToStr(req.UserID), req.Password); err != nil {
+		respondErr(c, http.StatusConflict, "user is already registered", err)
+		return
+	}
+
+	// 3. Only now add the 'names' row, so every registered user has a display name
+	if err := upsertName(ctx, tx, req.UserID, req.UserName); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to upsert user in names table", err)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to commit registration", err)
+		return
+	}
+
+	// 4. Start a session and issue tokens
+	respondWithSession(c, http.StatusCreated, req.UserID, req.UserName)
+}
+
+// POST /auth/login - check credentials and start a session.
+func loginUser(c *gin.Context) {
+	var req loginReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body, user_id and password are required"})
+		return
+	}
+
+	if !auth.Login_user(uidToStr(req.UserID), req.Password) {
+		respondErr(c, http.StatusUnauthorized, "invalid credentials", nil)
+		return
+	}
+
+	// Users created before registration existed may not have a 'names' row yet.
+	var name string
+	err := conn.QueryRow(context.Background(), `SELECT name FROM names WHERE id=$1`, req.UserID).Scan(&name)
+	if err != nil && err != pgx.ErrNoRows {
+		respondErr(c, http.StatusInternalServerError, "failed to load user profile", err)
+		return
+	}
+
+	respondWithSession(c, http.StatusOK, req.UserID, name)
+}
+
+// GET /auth/me - profile of the authenticated user
+func getMe(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+
+	var name string
+	err := conn.QueryRow(context.Background(), `SELECT name FROM names WHERE id=$1`, userID).Scan(&name)
+	if err == pgx.ErrNoRows {
+		respondErr(c, http.StatusNotFound, "user profile not found, register first", nil)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to load user profile", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "user_name": name})
+}
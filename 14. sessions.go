@@ -0,0 +1,204 @@
+// handlers_sessions.go
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// refreshTokenTTL is how long a session (and its refresh token) lives (PF_REFRESH_TTL).
+var refreshTokenTTL = envDuration("PF_REFRESH_TTL", 30*24*time.Hour)
+
+// --- Models ---
+
+// refreshReq is the JSON body for POST /auth/refresh.
+type refreshReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// logoutReq is the optional JSON body for POST /auth/logout.
+type logoutReq struct {
+	All bool `json:"all"` // "log out everywhere"
+}
+
+// --- Handlers ---
+
+// POST /auth/refresh - exchange a refresh token for a new access token.
+// The refresh token is rotated: the old one stops working.
+func refreshSession(c *gin.Context) {
+	var req refreshReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body, refresh_token is required"})
+		return
+	}
+
+	newToken, err := newRefreshToken()
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to generate refresh token", err)
+		return
+	}
+
+	// Rotate atomically; only an active session matching the presented token is updated.
+	// Suspending a user revokes their sessions, so no suspension check is needed here.
+	var sid int64
+	var userID int
+	err = conn.QueryRow(context.Background(),
+		`UPDATE auth_sessions SET refresh_hash=$2, last_used_at=now()
+         WHERE refresh_hash=$1 AND revoked_at IS NULL AND expires_at > now()
+         RETURNING id, user_id`,
+		hashToken(req.RefreshToken), hashToken(newToken)).Scan(&sid, &userID)
+	if err == pgx.ErrNoRows {
+		respondErr(c, http.StatusUnauthorized, "invalid or expired refresh token", nil)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to refresh session", err)
+		return
+	}
+
+	access, exp, err := jwtKeys.signAccessToken(userID, sid)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to issue token", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  access,
+		"token_type":    "Bearer",
+		"expires_at":    exp,
+		"refresh_token": newToken,
+		"user_id":       userID,
+	})
+}
+
+// POST /auth/logout - revoke the current session, or all of the
+// user's sessions when the body is {"all": true}.
+func logout(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+
+	var req logoutReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+
+	if req.All {
+		n, err := revokeUserSessions(context.Background(), userID)
+		if err != nil {
+			respondErr(c, http.StatusInternalServerError, "failed to revoke sessions", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "logged out everywhere", "sessions_revoked": n})
+		return
+	}
+
+	sid, ok := c.Get("session_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is not bound to a session, use {\"all\": true}"})
+		return
+	}
+	if _, err := conn.Exec(context.Background(),
+		`UPDATE auth_sessions SET revoked_at=now() WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL`,
+		sid, userID); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to revoke session", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "logged out"})
+}
+
+// --- Helpers ---
+
+// respondWithSession starts a new session for userID and writes the token response.
+// Suspended users get the same 403 as on protected routes instead.
+func respondWithSession(c *gin.Context, code int, userID int, userName string) {
+	suspension, err := activeSuspension(context.Background(), userID)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to check account status", err)
+		return
+	}
+	if suspension != nil {
+		respondSuspended(c, suspension)
+		return
+	}
+
+	refresh, err := newRefreshToken()
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to generate refresh token", err)
+		return
+	}
+
+	var sid int64
+	if err := conn.QueryRow(context.Background(),
+		`INSERT INTO auth_sessions (user_id, refresh_hash, expires_at) VALUES ($1, $2, $3) RETURNING id`,
+		userID, hashToken(refresh), time.Now().Add(refreshTokenTTL)).Scan(&sid); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to create session", err)
+		return
+	}
+
+	access, exp, err := jwtKeys.signAccessToken(userID, sid)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to issue token", err)
+		return
+	}
+
+	c.JSON(code, gin.H{
+		"access_token":  access,
+		"token_type":    "Bearer",
+		"expires_at":    exp,
+		"refresh_token": refresh,
+		"user_id":       userID,
+		"user_name":     userName,
+	})
+}
+
+// sessionActive reports whether session sid of userID is neither revoked nor expired.
+func sessionActive(ctx context.Context, sid int64, userID int) (bool, error) {
+	var active bool
+	err := conn.QueryRow(ctx,
+		`SELECT revoked_at IS NULL AND expires_at > now() FROM auth_sessions WHERE id=$1 AND user_id=$2`,
+		sid, userID).Scan(&active)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	return active, err
+}
+
+// revokeUserSessions ends every active session of userID and returns how many were revoked.
+func revokeUserSessions(ctx context.Context, userID int) (int64, error) {
+	tag, err := conn.Exec(ctx,
+		`UPDATE auth_sessions SET revoked_at=now() WHERE user_id=$1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// newRefreshToken returns a random opaque refresh token.
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+	return "pfr_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is how refresh tokens are stored; the raw token is never persisted.
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
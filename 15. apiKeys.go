@@ -0,0 +1,256 @@
+// handlers_api_keys.go
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------------------- Scopes ----------------------
+
+const (
+	ScopeProjectsRead   = "projects:read"     // read-only access to user routes
+	ScopeProjectsSubmit = "projects:submit"   // submit projects for review
+	ScopeProjectsManage = "projects:manage"   // manage projects the owner maintains
+	ScopeAdminReview    = "admin:review"      // /admin routes (review queue)
+	ScopeSuperadmin     = "superadmin:manage" // /superadmin routes
+)
+
+// knownScopes is the set of scopes a key may be created with.
+var knownScopes = map[string]bool{
+	ScopeProjectsRead:   true,
+	ScopeProjectsSubmit: true,
+	ScopeProjectsManage: true,
+	ScopeAdminReview:    true,
+	ScopeSuperadmin:     true,
+}
+
+// roleDefaultScopes is the scope RequireRole(role) demands from API keys
+// when the route does not name its own.
+var roleDefaultScopes = map[string]string{
+	"user":       ScopeProjectsRead,
+	"admin":      ScopeAdminReview,
+	"superadmin": ScopeSuperadmin,
+}
+
+// apiKeyPrefix marks a bearer token as a personal API key rather than a JWT.
+const apiKeyPrefix = "pfk_"
+
+// --- Models ---
+
+// APIKey represents a record in the 'api_keys' table (without the hash).
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// createAPIKeyReq is the JSON body for POST /auth/api-keys.
+type createAPIKeyReq struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"` // optional, never expires when omitted
+}
+
+// --- Handlers ---
+
+// POST /auth/api-keys - create a personal API key.
+// The full key is only returned in this response.
+func createAPIKey(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+	if _, viaKey := c.Get("api_key_id"); viaKey {
+		respondErr(c, http.StatusForbidden, "API keys cannot manage API keys", nil)
+		return
+	}
+
+	var req createAPIKeyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body, name and scopes are required"})
+		return
+	}
+	for _, s := range req.Scopes {
+		if !knownScopes[s] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown scope %q", s)})
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	prefix, raw, err := newAPIKey()
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to generate API key", err)
+		return
+	}
+
+	var id int64
+	if err := conn.QueryRow(context.Background(),
+		`INSERT INTO api_keys (user_id, name, key_prefix, key_hash, scopes, expires_at)
+         VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		userID, req.Name, prefix, hashToken(raw), req.Scopes, req.ExpiresAt).Scan(&id); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to create API key", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":         id,
+		"key":        raw,
+		"prefix":     prefix,
+		"scopes":     req.Scopes,
+		"expires_at": req.ExpiresAt,
+	})
+}
+
+// GET /auth/api-keys - list the user's API keys (never the secrets)
+func listAPIKeys(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+
+	rows, err := conn.Query(context.Background(),
+		`SELECT id, user_id, name, key_prefix, scopes, created_at, expires_at, last_used_at, revoked_at
+         FROM api_keys WHERE user_id=$1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch API keys", err)
+		return
+	}
+	out, err := pgx.CollectRows(rows, pgx.RowToStructByPos[APIKey])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+// DELETE /auth/api-keys/:id - revoke one of the user's API keys
+func revokeAPIKey(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+	if _, viaKey := c.Get("api_key_id"); viaKey {
+		respondErr(c, http.StatusForbidden, "API keys cannot manage API keys", nil)
+		return
+	}
+
+	keyID, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key id"})
+		return
+	}
+
+	cmdTag, err := conn.Exec(context.Background(),
+		`UPDATE api_keys SET revoked_at=now() WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL`,
+		keyID, userID)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to revoke API key", err)
+		return
+	}
+	if cmdTag.RowsAffected() == 0 {
+		respondErr(c, http.StatusNotFound, "API key not found or already revoked", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "revoked", "id": keyID})
+}
+
+// --- Helpers ---
+
+// newAPIKey returns a key of the form "pfk_<prefix>.<secret>" and its lookup prefix.
+func newAPIKey() (prefix, raw string, err error) {
+	p := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(p); err != nil {
+		return "", "", fmt.Errorf("read random: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("read random: %w", err)
+	}
+	prefix = hex.EncodeToString(p)
+	return prefix, apiKeyPrefix + prefix + "." + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// lookupAPIKey returns the active key matching raw, or nil if there is none.
+func lookupAPIKey(ctx context.Context, raw string) (*APIKey, error) {
+	prefix, ok := parseAPIKey(raw)
+	if !ok {
+		return nil, nil
+	}
+
+	var k APIKey
+	var hash []byte
+	err := conn.QueryRow(ctx,
+		`SELECT id, user_id, name, key_prefix, scopes, key_hash FROM api_keys
+         WHERE key_prefix=$1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())`,
+		prefix).Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &hash)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(hash, hashToken(raw)) != 1 {
+		return nil, nil
+	}
+
+	if _, err := conn.Exec(ctx, `UPDATE api_keys SET last_used_at=now() WHERE id=$1`, k.ID); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// parseAPIKey returns the lookup prefix of a "pfk_<prefix>.<secret>" key, or
+// false if raw does not have that shape.
+func parseAPIKey(raw string) (string, bool) {
+	rest, isKey := strings.CutPrefix(raw, apiKeyPrefix)
+	prefix, secret, found := strings.Cut(rest, ".")
+	if !isKey || !found || prefix == "" || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+// missingScopes returns the scopes the request's API key lacks.
+// Requests authenticated with a user token are not scope-limited.
+func missingScopes(c *gin.Context, required []string) []string {
+	val, viaKey := c.Get("api_key_scopes")
+	if !viaKey {
+		return nil
+	}
+	have, _ := val.([]string)
+
+	var missing []string
+	for _, r := range required {
+		if !slices.Contains(have, r) {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
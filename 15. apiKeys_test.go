@@ -0,0 +1,48 @@
+// 15. apiKeys_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAPIKey(t *testing.T) {
+	tests := []struct {
+		raw        string
+		wantPrefix string
+		wantOK     bool
+	}{
+		{"pfk_0a1b2c3d4e5f.c2VjcmV0", "0a1b2c3d4e5f", true},
+		{"pfk_abc.secret.with.dots", "abc", true},
+		{"pfk_0a1b2c3d4e5f", "", false},  // no secret
+		{"pfk_0a1b2c3d4e5f.", "", false}, // empty secret
+		{"pfk_.secret", "", false},       // empty prefix
+		{"0a1b2c3d4e5f.secret", "", false},
+		{"xpfk_0a1b2c3d4e5f.secret", "", false},
+		{"eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig", "", false}, // a JWT
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		prefix, ok := parseAPIKey(tt.raw)
+		if prefix != tt.wantPrefix || ok != tt.wantOK {
+			t.Errorf("parseAPIKey(%q) = %q, %v; want %q, %v", tt.raw, prefix, ok, tt.wantPrefix, tt.wantOK)
+		}
+	}
+}
+
+func TestNewAPIKeyParses(t *testing.T) {
+	prefix, raw, err := newAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(raw, apiKeyPrefix) || len(prefix) != 12 {
+		t.Fatalf("newAPIKey() = %q, %q", prefix, raw)
+	}
+	got, ok := parseAPIKey(raw)
+	if !ok || got != prefix {
+		t.Fatalf("parseAPIKey(%q) = %q, %v; want %q", raw, got, ok, prefix)
+	}
+	if _, again, _ := newAPIKey(); again == raw {
+		t.Fatal("newAPIKey returned the same key twice")
+	}
+}
@@ -0,0 +1,312 @@
+// handlers_oidc.go
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/oauth2"
+)
+
+// oidcStateTTL bounds how long a started login may take to come back.
+const oidcStateTTL = 10 * time.Minute
+
+// oidcUserIDStart is the first ID oidc_user_id_seq hands out; registerUser
+// keeps password accounts below it.
+const oidcUserIDStart = 1000000000
+
+// errIdentityIDTaken is returned by resolveUser when a first login's user ID
+// claim names an account that already exists.
+var errIdentityIDTaken = errors.New("user id already in use")
+
+// --- Models ---
+
+// oidcConfig is the campus identity provider setup (nil when SSO is disabled).
+type oidcConfig struct {
+	issuer      string
+	provider    *oidc.Provider
+	verifier    *oidc.IDTokenVerifier
+	oauth       oauth2.Config
+	nameClaim   string // display name for the 'names' row
+	userIDClaim string // optional claim that already holds a numeric names.id
+
+	// Whether a first login may take over an existing names.id from the ID
+	// claim; only safe if the IdP alone controls that claim.
+	allowLinkExisting bool
+}
+
+var oidcLogin *oidcConfig
+
+// initOIDCFromEnv discovers the identity provider configured by:
+//   - PF_OIDC_ISSUER, PF_OIDC_CLIENT_ID, PF_OIDC_REDIRECT_URL (required)
+//   - PF_OIDC_CLIENT_SECRET (optional, public clients rely on PKCE alone)
+//   - PF_OIDC_SCOPES (default "openid profile email")
+//   - PF_OIDC_NAME_CLAIM (default "name"), PF_OIDC_USER_ID_CLAIM (optional)
+//   - PF_OIDC_ALLOW_LINK_EXISTING=1 to let PF_OIDC_USER_ID_CLAIM link a new
+//     subject to an account that already exists (refused by default)
+//
+// The issuer may be any discoverable provider, including a local mock IdP
+// such as http://localhost:9000 for end-to-end tests.
+func initOIDCFromEnv(ctx context.Context) (*oidcConfig, error) {
+	issuer := os.Getenv("PF_OIDC_ISSUER")
+	if issuer == "" {
+		return nil, nil
+	}
+	clientID := os.Getenv("PF_OIDC_CLIENT_ID")
+	redirect := os.Getenv("PF_OIDC_REDIRECT_URL")
+	if clientID == "" || redirect == "" {
+		return nil, fmt.Errorf("PF_OIDC_CLIENT_ID and PF_OIDC_REDIRECT_URL are required with PF_OIDC_ISSUER")
+	}
+
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s: %w", issuer, err)
+	}
+
+	scopes := strings.Fields(os.Getenv("PF_OIDC_SCOPES"))
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	nameClaim := os.Getenv("PF_OIDC_NAME_CLAIM")
+	if nameClaim == "" {
+		nameClaim = "name"
+	}
+
+	return &oidcConfig{
+		issuer:   issuer,
+		provider: provider,
+		verifier: provider.Verifier(&oidc.Config{ClientID: clientID}),
+		oauth: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("PF_OIDC_CLIENT_SECRET"),
+			RedirectURL:  redirect,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		nameClaim:         nameClaim,
+		userIDClaim:       os.Getenv("PF_OIDC_USER_ID_CLAIM"),
+		allowLinkExisting: os.Getenv("PF_OIDC_ALLOW_LINK_EXISTING") == "1",
+	}, nil
+}
+
+// --- Handlers ---
+
+// GET /auth/oidc/login - start an authorization-code + PKCE login
+// by redirecting to the identity provider.
+func startOIDCLogin(c *gin.Context) {
+	if oidcLogin == nil {
+		respondErr(c, http.StatusServiceUnavailable, "single sign-on is not configured", nil)
+		return
+	}
+
+	state, err1 := randomURLToken()
+	nonce, err2 := randomURLToken()
+	if err1 != nil || err2 != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to start login", fmt.Errorf("%v %v", err1, err2))
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	// Stored server-side so the callback can land on any instance.
+	if _, err := conn.Exec(context.Background(),
+		`INSERT INTO oidc_login_states (state, nonce, code_verifier) VALUES ($1, $2, $3)`,
+		state, nonce, verifier); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to start login", err)
+		return
+	}
+
+	url := oidcLogin.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	c.Redirect(http.StatusFound, url)
+}
+
+// GET /auth/oidc/callback - finish the login: exchange the code, verify the
+// ID token and start a session for the mapped names.id.
+func finishOIDCLogin(c *gin.Context) {
+	if oidcLogin == nil {
+		respondErr(c, http.StatusServiceUnavailable, "single sign-on is not configured", nil)
+		return
+	}
+	if e := c.Query("error"); e != "" {
+		respondErr(c, http.StatusUnauthorized, "identity provider denied login: "+e, nil)
+		return
+	}
+
+	// 1. Consume the login state (single use)
+	var nonce, verifier string
+	err := conn.QueryRow(context.Background(),
+		`DELETE FROM oidc_login_states WHERE state=$1 AND created_at > $2
+         RETURNING nonce, code_verifier`,
+		c.Query("state"), time.Now().Add(-oidcStateTTL)).Scan(&nonce, &verifier)
+	if err == pgx.ErrNoRows {
+		respondErr(c, http.StatusBadRequest, "unknown or expired login state", nil)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to load login state", err)
+		return
+	}
+
+	// 2. Exchange the code using the PKCE verifier
+	ctx := context.Background()
+	tok, err := oidcLogin.oauth.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(verifier))
+	if err != nil {
+		respondErr(c, http.StatusUnauthorized, "code exchange failed", err)
+		return
+	}
+	rawID, ok := tok.Extra("id_token").(string)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "no id_token in token response", nil)
+		return
+	}
+
+	// 3. Verify the ID token
+	idToken, err := oidcLogin.verifier.Verify(ctx, rawID)
+	if err != nil {
+		respondErr(c, http.StatusUnauthorized, "invalid id_token", err)
+		return
+	}
+	if idToken.Nonce != nonce {
+		respondErr(c, http.StatusUnauthorized, "id_token nonce mismatch", nil)
+		return
+	}
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		respondErr(c, http.StatusUnauthorized, "unreadable id_token claims", err)
+		return
+	}
+
+	// 4. Map the external subject to a names.id
+	userID, userName, err := oidcLogin.resolveUser(ctx, idToken.Subject, claims)
+	if err == errIdentityIDTaken {
+		respondErr(c, http.StatusConflict, "this account already exists; ask an administrator to link it", nil)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to map identity", err)
+		return
+	}
+
+	respondWithSession(c, http.StatusOK, userID, userName)
+}
+
+// --- Helpers ---
+
+// resolveUser returns the names.id linked to subject, linking a new one and
+// creating its 'names' row on first login. An ID taken from the user ID claim
+// must not belong to anyone yet (errIdentityIDTaken) unless the operator
+// allows linking existing accounts, whose names are then left as they are.
+func (o *oidcConfig) resolveUser(ctx context.Context, subject string, claims map[string]interface{}) (int, string, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int
+	var userName string
+	err = tx.QueryRow(ctx,
+		`SELECT i.user_id, COALESCE(n.name, '') FROM oidc_identities i
+         LEFT JOIN names n ON n.id = i.user_id
+         WHERE i.issuer=$1 AND i.subject=$2`, o.issuer, subject).Scan(&userID, &userName)
+	if err == nil {
+		return userID, userName, nil
+	}
+	if err != pgx.ErrNoRows {
+		return 0, "", err
+	}
+
+	// First login: take the ID from the mapped claim, or allocate one.
+	if userID, err = o.claimUserID(claims); err != nil {
+		return 0, "", err
+	}
+	if userID == 0 {
+		// Skip IDs someone already holds, e.g. assigned by hand in the reserved range
+		for inUse := true; inUse; {
+			if err := tx.QueryRow(ctx, `SELECT nextval('oidc_user_id_seq')`).Scan(&userID); err != nil {
+				return 0, "", fmt.Errorf("allocate user id: %w", err)
+			}
+			if _, inUse, err = userIDInUse(ctx, tx, userID); err != nil {
+				return 0, "", fmt.Errorf("check user id: %w", err)
+			}
+		}
+	} else {
+		existing, inUse, err := userIDInUse(ctx, tx, userID)
+		if err != nil {
+			return 0, "", fmt.Errorf("check user id: %w", err)
+		}
+		if inUse {
+			if !o.allowLinkExisting {
+				return 0, "", errIdentityIDTaken
+			}
+			if existing != nil {
+				return userID, *existing, o.link(ctx, tx, subject, userID)
+			}
+		}
+	}
+
+	userName = claimString(claims, o.nameClaim)
+	if userName == "" {
+		userName = claimString(claims, "preferred_username")
+	}
+	if userName == "" {
+		userName = subject
+	}
+
+	if err := upsertName(ctx, tx, userID, userName); err != nil {
+		return 0, "", fmt.Errorf("upsert names: %w", err)
+	}
+	return userID, userName, o.link(ctx, tx, subject, userID)
+}
+
+// link records that subject logs in as userID and commits tx.
+func (o *oidcConfig) link(ctx context.Context, tx pgx.Tx, subject string, userID int) error {
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO oidc_identities (issuer, subject, user_id) VALUES ($1, $2, $3)`,
+		o.issuer, subject, userID); err != nil {
+		return fmt.Errorf("link identity: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// claimUserID reads the configured user ID claim, or returns 0 if none is configured.
+func (o *oidcConfig) claimUserID(claims map[string]interface{}) (int, error) {
+	if o.userIDClaim == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(claimString(claims, o.userIDClaim))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("claim %q is not a valid user id", o.userIDClaim)
+	}
+	return id, nil
+}
+
+// claimString renders a string or numeric claim as a string.
+func claimString(claims map[string]interface{}, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// randomURLToken returns 32 random bytes, base64url encoded.
+func randomURLToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
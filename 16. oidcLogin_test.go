@@ -0,0 +1,447 @@
+// 16. oidcLogin_test.go
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/oauth2"
+)
+
+const (
+	testOIDCClientID    = "pf-test"
+	testOIDCRedirectURL = "http://pf.test/auth/oidc/callback"
+)
+
+// mockIdP is a minimal OpenID provider: discovery, an authorize endpoint that
+// logs the next user in straight away, a PKCE-checking token endpoint and JWKS.
+type mockIdP struct {
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	claims jwt.MapClaims        // extra claims of the next login
+	tamper func(jwt.MapClaims)  // optional change to the next ID token
+	codes  map[string]mockGrant // issued authorization codes
+}
+
+type mockGrant struct {
+	nonce, challenge string
+	claims           jwt.MapClaims
+	tamper           func(jwt.MapClaims)
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, codes: map[string]mockGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/jwks", idp.jwks)
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+// login sets the claims (and optional tampering) of the next login.
+func (idp *mockIdP) login(claims jwt.MapClaims, tamper func(jwt.MapClaims)) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.claims, idp.tamper = claims, tamper
+}
+
+func (idp *mockIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	u := idp.srv.URL
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                u,
+		"authorization_endpoint":                u + "/authorize",
+		"token_endpoint":                        u + "/token",
+		"jwks_uri":                              u + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (idp *mockIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE required", http.StatusBadRequest)
+		return
+	}
+	code := fmt.Sprintf("code-%d", time.Now().UnixNano())
+	idp.mu.Lock()
+	idp.codes[code] = mockGrant{nonce: q.Get("nonce"), challenge: q.Get("code_challenge"),
+		claims: idp.claims, tamper: idp.tamper}
+	idp.mu.Unlock()
+
+	back, _ := url.Parse(q.Get("redirect_uri"))
+	back.RawQuery = url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, back.String(), http.StatusFound)
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	idp.mu.Lock()
+	g, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   idp.srv.URL,
+		"aud":   testOIDCClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Minute).Unix(),
+		"nonce": g.nonce,
+	}
+	for k, v := range g.claims {
+		claims[k] = v
+	}
+	if g.tamper != nil {
+		g.tamper(claims)
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	t.Header["kid"] = "mock"
+	idToken, err := t.SignedString(idp.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "mock-access",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+func (idp *mockIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := idp.key.PublicKey
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA", "kid": "mock", "use": "sig", "alg": "RS256",
+		"n": base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+// newTestOIDC configures SSO against idp the way main does.
+func newTestOIDC(t *testing.T, idp *mockIdP, env map[string]string) *oidcConfig {
+	t.Helper()
+	t.Setenv("PF_OIDC_ISSUER", idp.srv.URL)
+	t.Setenv("PF_OIDC_CLIENT_ID", testOIDCClientID)
+	t.Setenv("PF_OIDC_REDIRECT_URL", testOIDCRedirectURL)
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	cfg, err := initOIDCFromEnv(context.Background())
+	if err != nil {
+		t.Fatalf("initOIDCFromEnv: %v", err)
+	}
+	return cfg
+}
+
+// authorizeAt follows an authorization URL to the IdP and returns the
+// query it redirects back to the callback with.
+func authorizeAt(t *testing.T, authURL string) url.Values {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if got := loc.Scheme + "://" + loc.Host + loc.Path; got != testOIDCRedirectURL {
+		t.Fatalf("redirected to %s, want %s", got, testOIDCRedirectURL)
+	}
+	return loc.Query()
+}
+
+// TestOIDCMockIdP checks the discovered configuration end to end against the
+// mock IdP: PKCE, the ID token signature, audience, expiry and nonce.
+func TestOIDCMockIdP(t *testing.T) {
+	idp := newMockIdP(t)
+	cfg := newTestOIDC(t, idp, nil)
+
+	tests := []struct {
+		name          string
+		tamper        func(jwt.MapClaims)
+		wrongVerifier bool
+		wantErr       string // "" for a successful login
+	}{
+		{name: "valid login"},
+		{name: "wrong PKCE verifier", wrongVerifier: true, wantErr: "invalid_grant"},
+		{name: "other audience", tamper: func(c jwt.MapClaims) { c["aud"] = "someone-else" }, wantErr: "audience"},
+		{name: "other issuer", tamper: func(c jwt.MapClaims) { c["iss"] = "https://evil.test" }, wantErr: "different provider"},
+		{name: "expired", tamper: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, wantErr: "expired"},
+		{name: "replayed nonce", tamper: func(c jwt.MapClaims) { c["nonce"] = "old" }, wantErr: "nonce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp.login(jwt.MapClaims{"sub": "alice", "name": "Alice"}, tt.tamper)
+			ctx := context.Background()
+			state, nonce := "state-"+tt.name, "nonce-"+tt.name
+			verifier := oauth2.GenerateVerifier()
+
+			q := authorizeAt(t, cfg.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)))
+			if q.Get("state") != state {
+				t.Fatalf("state = %q, want %q", q.Get("state"), state)
+			}
+			if tt.wrongVerifier {
+				verifier = oauth2.GenerateVerifier()
+			}
+
+			err := func() error {
+				tok, err := cfg.oauth.Exchange(ctx, q.Get("code"), oauth2.VerifierOption(verifier))
+				if err != nil {
+					return err
+				}
+				rawID, _ := tok.Extra("id_token").(string)
+				idToken, err := cfg.verifier.Verify(ctx, rawID)
+				if err != nil {
+					return err
+				}
+				if idToken.Nonce != nonce {
+					return fmt.Errorf("nonce mismatch")
+				}
+				var claims map[string]interface{}
+				if err := idToken.Claims(&claims); err != nil {
+					return err
+				}
+				if idToken.Subject != "alice" || claimString(claims, cfg.nameClaim) != "Alice" {
+					return fmt.Errorf("unexpected identity %q %v", idToken.Subject, claims)
+				}
+				return nil
+			}()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("login failed: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("login succeeded, want error containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("error %q does not mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClaimUserID(t *testing.T) {
+	tests := []struct {
+		name    string
+		claim   string
+		claims  map[string]interface{}
+		want    int
+		wantErr bool
+	}{
+		{name: "no claim configured", claims: map[string]interface{}{"roll": "42"}, want: 0},
+		{name: "string claim", claim: "roll", claims: map[string]interface{}{"roll": " 42 "}, want: 42},
+		{name: "numeric claim", claim: "roll", claims: map[string]interface{}{"roll": float64(42)}, want: 42},
+		{name: "missing claim", claim: "roll", claims: map[string]interface{}{}, wantErr: true},
+		{name: "not a number", claim: "roll", claims: map[string]interface{}{"roll": "abc"}, wantErr: true},
+		{name: "zero", claim: "roll", claims: map[string]interface{}{"roll": "0"}, wantErr: true},
+		{name: "negative", claim: "roll", claims: map[string]interface{}{"roll": float64(-7)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&oidcConfig{userIDClaim: tt.claim}).claimUserID(tt.claims)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("claimUserID = %d, %v; want %d (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestOIDCLoginFlow runs GET /auth/oidc/login and /auth/oidc/callback against
+// the mock IdP and a real database (PF_TEST_DB_CONN), including refusing to
+// link a first login to an account that already exists.
+func TestOIDCLoginFlow(t *testing.T) {
+	dsn := os.Getenv("PF_TEST_DB_CONN")
+	if dsn == "" {
+		t.Skip("PF_TEST_DB_CONN not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	prevConn, prevKeys, prevOIDC := conn, jwtKeys, oidcLogin
+	t.Cleanup(func() { conn, jwtKeys, oidcLogin = prevConn, prevKeys, prevOIDC })
+	conn = pool
+	if err := ensureSchema(ctx); err != nil {
+		t.Fatalf("ensureSchema: %v", err)
+	}
+	t.Setenv("PF_JWT_HS256_KEYS", "test="+strings.Repeat("k", 32))
+	if jwtKeys, err = loadJWTKeySetFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/auth/oidc/login", startOIDCLogin)
+	r.GET("/auth/oidc/callback", finishOIDCLogin)
+
+	idp := newMockIdP(t)
+	run := time.Now().UnixNano()
+	existingID := int(run%1_000_000) + 500_000_000
+	if err := upsertName(ctx, conn, existingID, "Existing User"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Exec(ctx, `DELETE FROM oidc_identities WHERE issuer=$1`, idp.srv.URL)
+		conn.Exec(ctx, `DELETE FROM names WHERE id IN ($1, $2)`, existingID, existingID+1)
+	})
+
+	// login drives the whole flow for sub and returns the callback's response.
+	login := func(t *testing.T, sub string, extra jwt.MapClaims) (int, map[string]interface{}) {
+		t.Helper()
+		claims := jwt.MapClaims{"sub": sub, "name": "Mallory"}
+		for k, v := range extra {
+			claims[k] = v
+		}
+		idp.login(claims, nil)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+		if w.Code != http.StatusFound {
+			t.Fatalf("login: status %d: %s", w.Code, w.Body)
+		}
+		q := authorizeAt(t, w.Header().Get("Location"))
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+q.Encode(), nil))
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body
+	}
+	sub := func(s string) string { return fmt.Sprintf("%s-%d", s, run) }
+
+	t.Run("new subject gets an allocated id", func(t *testing.T) {
+		oidcLogin = newTestOIDC(t, idp, nil)
+		code, body := login(t, sub("new"), nil)
+		if code != http.StatusOK || body["access_token"] == nil {
+			t.Fatalf("status %d: %v", code, body)
+		}
+		id := int(body["user_id"].(float64))
+		if id < 1_000_000_000 {
+			t.Fatalf("user_id %d not from oidc_user_id_seq", id)
+		}
+		if code, again := login(t, sub("new"), nil); code != http.StatusOK || int(again["user_id"].(float64)) != id {
+			t.Fatalf("second login: status %d, %v; want user_id %d", code, again, id)
+		}
+	})
+
+	t.Run("allocated ids skip accounts already in the range", func(t *testing.T) {
+		oidcLogin = newTestOIDC(t, idp, nil)
+		var last int
+		if err := conn.QueryRow(ctx, `SELECT nextval('oidc_user_id_seq')`).Scan(&last); err != nil {
+			t.Fatal(err)
+		}
+		squatted := last + 1
+		if err := upsertName(ctx, conn, squatted, "Pre-registered"); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Exec(ctx, `DELETE FROM names WHERE id=$1`, squatted) })
+
+		code, body := login(t, sub("after-squat"), nil)
+		if code != http.StatusOK {
+			t.Fatalf("status %d: %v", code, body)
+		}
+		if id := int(body["user_id"].(float64)); id <= squatted {
+			t.Fatalf("user_id %d, want an id past the pre-registered %d", id, squatted)
+		}
+	})
+
+	t.Run("claim naming an existing account is refused", func(t *testing.T) {
+		oidcLogin = newTestOIDC(t, idp, map[string]string{"PF_OIDC_USER_ID_CLAIM": "roll"})
+		code, body := login(t, sub("taker"), jwt.MapClaims{"roll": fmt.Sprint(existingID)})
+		if code != http.StatusConflict {
+			t.Fatalf("status %d: %v; want 409", code, body)
+		}
+		var name string
+		conn.QueryRow(ctx, `SELECT name FROM names WHERE id=$1`, existingID).Scan(&name)
+		if name != "Existing User" {
+			t.Fatalf("existing name overwritten with %q", name)
+		}
+	})
+
+	t.Run("claim naming an unused id is linked", func(t *testing.T) {
+		oidcLogin = newTestOIDC(t, idp, map[string]string{"PF_OIDC_USER_ID_CLAIM": "roll"})
+		code, body := login(t, sub("fresh"), jwt.MapClaims{"roll": float64(existingID + 1)})
+		if code != http.StatusOK || int(body["user_id"].(float64)) != existingID+1 || body["user_name"] != "Mallory" {
+			t.Fatalf("status %d: %v", code, body)
+		}
+	})
+
+	t.Run("operator may allow linking existing accounts", func(t *testing.T) {
+		oidcLogin = newTestOIDC(t, idp, map[string]string{
+			"PF_OIDC_USER_ID_CLAIM": "roll", "PF_OIDC_ALLOW_LINK_EXISTING": "1"})
+		code, body := login(t, sub("linked"), jwt.MapClaims{"roll": fmt.Sprint(existingID)})
+		if code != http.StatusOK || int(body["user_id"].(float64)) != existingID || body["user_name"] != "Existing User" {
+			t.Fatalf("status %d: %v", code, body)
+		}
+	})
+
+	t.Run("expired login states are swept", func(t *testing.T) {
+		oidcLogin = newTestOIDC(t, idp, nil)
+		state := sub("stale")
+		if _, err := conn.Exec(ctx,
+			`INSERT INTO oidc_login_states (state, nonce, code_verifier, created_at) VALUES ($1, 'n', 'v', $2)`,
+			state, time.Now().Add(-2*oidcStateTTL)); err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?code=x&state="+state, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("callback with expired state: status %d, want 400", w.Code)
+		}
+		if err := sweepExpiredOIDCStates(ctx); err != nil {
+			t.Fatal(err)
+		}
+		var left int
+		conn.QueryRow(ctx, `SELECT count(*) FROM oidc_login_states WHERE state=$1`, state).Scan(&left)
+		if left != 0 {
+			t.Fatalf("expired state not swept")
+		}
+	})
+}
@@ -0,0 +1,31 @@
+// handlers_effective_roles.go
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /auth/me/roles - the authenticated user's effective roles
+func getMyRoles(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "roles": EffectiveRoles(c, uidToStr(userID))})
+}
+
+// GET /superadmin/users/:id/effective-roles - any user's effective roles
+// Only accessible by existing Superadmins.
+func getUserEffectiveRoles(c *gin.Context) {
+	userID, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "roles": EffectiveRoles(c, uidToStr(userID))})
+}
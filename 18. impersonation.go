@@ -0,0 +1,136 @@
+// handlers_impersonation.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// impersonationTTL is how long an impersonation token lives (PF_IMPERSONATION_TTL).
+var impersonationTTL = envDuration("PF_IMPERSONATION_TTL", 30*time.Minute)
+
+// --- Models ---
+
+// impersonateReq is the JSON body for POST /superadmin/impersonate.
+type impersonateReq struct {
+	UserID int    `json:"user_id" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// --- Middleware ---
+
+// ImpersonationMiddleware lets a superadmin act as another user, either per
+// request with "X-Act-As: <user_id>" or with a token from POST
+// /superadmin/impersonate. Everything after it sees the target's identity
+// and roles; the superadmin stays available via getActorID, which is what
+// deleted_by, revision authors and status history record, and every
+// impersonated request is written to the audit log.
+func ImpersonationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := getUserID(c)
+		tokenActor, viaToken := c.Get("actor_id_int")
+		header := c.GetHeader("X-Act-As")
+		if !viaToken && header == "" {
+			c.Next()
+			return
+		}
+
+		var actorID, targetID int
+		if viaToken {
+			if header != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "already impersonating, X-Act-As not allowed"})
+				c.Abort()
+				return
+			}
+			actorID, _ = tokenActor.(int)
+			targetID = userID
+		} else {
+			id, err := strconv.Atoi(header)
+			if err != nil || id <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid X-Act-As header"})
+				c.Abort()
+				return
+			}
+			actorID, targetID = userID, id
+		}
+
+		if _, viaKey := c.Get("api_key_id"); viaKey {
+			respondErr(c, http.StatusForbidden, "API keys cannot impersonate", nil)
+			c.Abort()
+			return
+		}
+		if err := checkImpersonation(c, actorID, targetID); err != nil {
+			respondErr(c, http.StatusForbidden, err.Error(), nil)
+			c.Abort()
+			return
+		}
+
+		c.Set("actor_id_int", actorID)
+		setAuthUser(c, targetID)
+		c.Header("X-Acting-As", uidToStr(targetID))
+
+		c.Next()
+
+		recordAudit(context.Background(), actorID, targetID, "impersonated_request",
+			fmt.Sprintf("%s %s -> %d", c.Request.Method, c.Request.URL.Path, c.Writer.Status()))
+	}
+}
+
+// --- Handlers ---
+
+// POST /superadmin/impersonate - open an impersonation session:
+// returns a short-lived, non-refreshable token for the target user.
+func startImpersonation(c *gin.Context) {
+	actorID, ok := getActorID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+
+	var req impersonateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body, user_id and reason are required"})
+		return
+	}
+	if err := checkImpersonation(c, actorID, req.UserID); err != nil {
+		respondErr(c, http.StatusForbidden, err.Error(), nil)
+		return
+	}
+
+	token, exp, err := jwtKeys.signImpersonationToken(req.UserID, actorID, impersonationTTL)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to issue token", err)
+		return
+	}
+	recordAudit(context.Background(), actorID, req.UserID, "impersonation_started", req.Reason)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_at":   exp,
+		"user_id":      req.UserID,
+		"actor_id":     actorID,
+	})
+}
+
+// --- Helpers ---
+
+// checkImpersonation enforces who may act as whom.
+func checkImpersonation(c *gin.Context, actorID, targetID int) error {
+	if actorID == targetID {
+		return errors.New("cannot impersonate yourself")
+	}
+	if !hasRoleFor(c, uidToStr(actorID), "superadmin") {
+		return errors.New("only superadmins can impersonate")
+	}
+	if hasRoleFor(c, uidToStr(targetID), "superadmin") {
+		return errors.New("impersonating another superadmin is forbidden")
+	}
+	return nil
+}
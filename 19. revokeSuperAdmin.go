@@ -0,0 +1,57 @@
+// handlers_roles.go
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DELETE /superadmin/roles/superadmin - Revoke superadmin role
+// Only accessible by existing Superadmins. Never leaves zero superadmins;
+// revoking yourself only files a request another superadmin must confirm.
+func revokeSuperAdmin(c *gin.Context) {
+	var req revokeUserReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body, user_id is required"})
+		return
+	}
+
+	actorID, ok := getActorID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+
+	// 1. Self-revocation waits for a second superadmin
+	if req.UserID == actorID {
+		id, err := createRoleChangeRequest(context.Background(),
+			roleChange{Action: roleActionRevoke, UserID: req.UserID, Space: SpaceSuperadmins}, actorID)
+		if err != nil {
+			respondErr(c, http.StatusInternalServerError, "failed to file revocation request", err)
+			return
+		}
+		respondRoleChangePending(c, id, "Self-revocation must be confirmed by another superadmin")
+		return
+	}
+
+	// 2. Revoke, unless it would remove the last superadmin
+	err := RemoveMemberFromSpace(req.UserID, SpaceSuperadmins)
+	if errors.Is(err, errLastSuperadmin) {
+		respondErr(c, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to revoke superadmin role", err)
+		return
+	}
+	recordAudit(context.Background(), actorID, req.UserID, "role_revoked", SpaceSuperadmins)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Superadmin role revoked successfully",
+		"user_id": req.UserID,
+		"space":   SpaceSuperadmins,
+	})
+}
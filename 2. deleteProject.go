@@ -3,10 +3,8 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 
-	"github.com/GCET-Open-Source-Foundation/auth"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
 )
@@ -22,43 +20,12 @@ func deleteProject(c *gin.Context) {
 		return
 	}
 
-	// 2. Get user's ID (int and string)
-	userID, ok := getUserID(c)
-	if !ok {
-		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+	// 2. Check permissions (creator, admin or superadmin; see projectPolicy)
+	if !authorizeProject(c, ActionDeleteProject, pid) {
 		return
 	}
-	val, _ := c.Get("user_id")
-	userIDStr := fmt.Sprintf("%v", val)
 
-	// 3. Get user's roles
-	isSuperAdmin := auth.Check_permissions(userIDStr, SpaceSuperadmins, MemberRole)
-	isAdmin := auth.Check_permissions(userIDStr, SpaceAdmins, MemberRole)
-
-	// 4. Find the project's creator to check ownership
-	var projectCreatorID int
-	err = conn.QueryRow(context.Background(),
-		`SELECT creator_id FROM approved_projects WHERE p_id=$1`, pid).Scan(&projectCreatorID)
-
-	if err == pgx.ErrNoRows {
-		respondErr(c, http.StatusNotFound, "project not found", nil)
-		return
-	}
-	if err != nil {
-		respondErr(c, http.StatusInternalServerError, "failed to check project ownership", err)
-		return
-	}
-
-	// 5. Check permissions
-	// User can delete if they are a Superadmin, an Admin, OR if they are the creator.
-	canDelete := isSuperAdmin || isAdmin || (userID == projectCreatorID)
-
-	if !canDelete {
-		respondErr(c, http.StatusForbidden, "you do not have permission to delete this project", nil)
-		return
-	}
-
-	// 6. Proceed with deletion (Archive and Delete)
+	// 3. Proceed with deletion (Archive and Delete)
 	tx, err := conn.Begin(context.Background())
 	if err != nil {
 		respondErr(c, http.StatusInternalServerError, "tx begin failed", err)
@@ -66,11 +33,9 @@ func deleteProject(c *gin.Context) {
 	}
 	defer tx.Rollback(context.Background())
 
-	// 1. Archive to deleted_projects
-	if _, err := tx.Exec(context.Background(),
-		`INSERT INTO deleted_projects (p_id, name, description, creator_id, creator_name) 
-         SELECT p_id, name, description, creator_id, creator_name 
-         FROM approved_projects WHERE p_id=$1`, pid); err != nil {
+	// 1. Archive a full snapshot (including the team) to deleted_projects
+	deletedBy, _ := getActorID(c)
+	if err := archiveProject(context.Background(), tx, pid, deletedBy); err != nil {
 		respondErr(c, http.StatusInternalServerError, "archive failed", err)
 		return
 	}
@@ -94,4 +59,22 @@ func deleteProject(c *gin.Context) {
 	}
 
 	c.Status(http.StatusNoContent)
-}
\ No newline at end of file
+}
+
+// archiveProject copies approved project pid, with its maintainers and
+// contributors, into deleted_projects so restoreDeletedProject can bring it
+// back. The caller deletes the project in the same tx.
+func archiveProject(ctx context.Context, tx pgx.Tx, pid, deletedBy int) error {
+	_, err := tx.Exec(ctx,
+		`INSERT INTO deleted_projects (p_id, name, description, creator_id, creator_name, category, tags, tech_stack,
+                                       status, start_date, end_date, maintainers, contributors, deleted_by) 
+         SELECT p_id, name, description, creator_id, creator_name, category, tags, tech_stack,
+                status, start_date, end_date,
+                COALESCE((SELECT jsonb_agg(jsonb_build_object('user_id', m.user_id, 'm_name', m.m_name) ORDER BY m.m_name)
+                          FROM maintainers m WHERE m.p_id=a.p_id), '[]'),
+                COALESCE((SELECT jsonb_agg(jsonb_build_object('user_id', ct.user_id, 'c_name', ct.c_name) ORDER BY ct.c_name)
+                          FROM contributors ct WHERE ct.p_id=a.p_id), '[]'),
+                NULLIF($2, 0)
+         FROM approved_projects a WHERE p_id=$1`, pid, deletedBy)
+	return err
+}
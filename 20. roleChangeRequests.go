@@ -0,0 +1,264 @@
+// handlers_role_requests.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Role change request actions.
+const (
+	roleActionGrant  = "grant"
+	roleActionRevoke = "revoke"
+)
+
+// roleConfirmWindow is how long a request waits for its second superadmin
+// (PF_ROLE_CONFIRM_WINDOW, default 24h).
+var roleConfirmWindow = envDuration("PF_ROLE_CONFIRM_WINDOW", 24*time.Hour)
+
+// --- Models ---
+
+// roleChange is a grant or revocation waiting for confirmation.
+type roleChange struct {
+	Action         string
+	UserID         int
+	UserName       string // grants only
+	Space          string
+	GrantExpiresAt *time.Time // grants only, see AssignMemberToSpaceUntil
+}
+
+// RoleChangeRequest represents a record in the 'role_change_requests' table.
+// Status is pending, confirmed, rejected or expired.
+type RoleChangeRequest struct {
+	ID             int64      `json:"id"`
+	Action         string     `json:"action"`
+	UserID         int        `json:"user_id"`
+	UserName       string     `json:"user_name"`
+	Space          string     `json:"space"`
+	GrantExpiresAt *time.Time `json:"grant_expires_at"`
+	RequestedBy    int        `json:"requested_by"`
+	RequestedAt    time.Time  `json:"requested_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	Status         string     `json:"status"`
+	DecidedBy      *int       `json:"decided_by"`
+	DecidedAt      *time.Time `json:"decided_at"`
+}
+
+// grantNeedsConfirmation reports whether granting space must be confirmed by
+// a second superadmin: always for superadmins, for admins when
+// PF_ADMIN_GRANTS_NEED_CONFIRMATION=1. Superadmins are counted in role_grants
+// (backfilled at startup, see backfillRoleGrants). While requestedBy is the
+// only superadmin nobody can confirm, so the request fails closed unless
+// PF_ROLE_BOOTSTRAP=1 lets the grant apply directly while setting up.
+func grantNeedsConfirmation(ctx context.Context, space string, requestedBy int) (bool, error) {
+	switch space {
+	case SpaceSuperadmins:
+	case SpaceAdmins:
+		if os.Getenv("PF_ADMIN_GRANTS_NEED_CONFIRMATION") != "1" {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+
+	var others int
+	err := conn.QueryRow(ctx,
+		`SELECT count(*) FROM role_grants WHERE space=$1 AND user_id <> $2`,
+		SpaceSuperadmins, requestedBy).Scan(&others)
+	if err != nil {
+		return true, err
+	}
+	return others > 0 || os.Getenv("PF_ROLE_BOOTSTRAP") != "1", nil
+}
+
+// createRoleChangeRequest files a pending role change and returns its ID.
+func createRoleChangeRequest(ctx context.Context, rc roleChange, requestedBy int) (int64, error) {
+	var id int64
+	err := conn.QueryRow(ctx,
+		`INSERT INTO role_change_requests (action, user_id, user_name, space, grant_expires_at, requested_by, expires_at)
+         VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		rc.Action, rc.UserID, rc.UserName, rc.Space, rc.GrantExpiresAt, requestedBy,
+		time.Now().Add(roleConfirmWindow)).Scan(&id)
+	if err == nil {
+		recordAudit(ctx, requestedBy, rc.UserID, "role_change_requested", fmt.Sprintf("%s %s #%d", rc.Action, rc.Space, id))
+	}
+	return id, err
+}
+
+// respondRoleChangePending writes the 202 for a request awaiting confirmation.
+func respondRoleChangePending(c *gin.Context, id int64, msg string) {
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    msg,
+		"request_id": id,
+		"status":     "pending",
+		"expires_at": time.Now().Add(roleConfirmWindow),
+	})
+}
+
+// --- Handlers ---
+
+// GET /superadmin/role-requests?status=&limit=&offset= - Role change requests, newest first
+// Pending requests past their window are reported as expired.
+func listRoleChangeRequests(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", "pending", "confirmed", "rejected", "expired":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, confirmed, rejected or expired"})
+		return
+	}
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	rows, err := conn.Query(context.Background(),
+		`SELECT * FROM (
+             SELECT id, action, user_id, user_name, space, grant_expires_at, requested_by, requested_at, expires_at,
+                    CASE WHEN status = 'pending' AND expires_at <= now() THEN 'expired' ELSE status END,
+                    decided_by, decided_at
+             FROM role_change_requests
+         ) r(id, action, user_id, user_name, space, grant_expires_at, requested_by, requested_at, expires_at, status, decided_by, decided_at)
+         WHERE $1 = '' OR status = $1
+         ORDER BY id DESC LIMIT $2 OFFSET $3`,
+		status, limit, offset)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch role change requests", err)
+		return
+	}
+	out, err := pgx.CollectRows(rows, pgx.RowToStructByPos[RoleChangeRequest])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": out, "limit": limit, "offset": offset})
+}
+
+// POST /superadmin/role-requests/:id/confirm - Confirm a pending role change
+// Must be a different superadmin than the requester, within the window.
+func confirmRoleChangeRequest(c *gin.Context) {
+	id, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request id"})
+		return
+	}
+	confirmerID, ok := getActorID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+	ctx := context.Background()
+
+	// 1. Claim the request atomically so it runs at most once
+	var rc roleChange
+	err = conn.QueryRow(ctx,
+		`UPDATE role_change_requests SET status='confirmed', decided_by=$2, decided_at=now()
+         WHERE id=$1 AND status='pending' AND expires_at > now() AND requested_by <> $2
+         RETURNING action, user_id, user_name, space, grant_expires_at`,
+		id, confirmerID).Scan(&rc.Action, &rc.UserID, &rc.UserName, &rc.Space, &rc.GrantExpiresAt)
+	if err == pgx.ErrNoRows {
+		respondUnclaimableRequest(c, id, confirmerID)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to confirm request", err)
+		return
+	}
+
+	// 2. Apply the change; on failure put the request back to pending
+	switch rc.Action {
+	case roleActionGrant:
+		err = AssignMemberToSpaceUntil(rc.UserID, rc.UserName, rc.Space, rc.GrantExpiresAt)
+	case roleActionRevoke:
+		err = RemoveMemberFromSpace(rc.UserID, rc.Space)
+	default:
+		err = fmt.Errorf("unknown role change action %q", rc.Action)
+	}
+	if err != nil {
+		if _, rbErr := conn.Exec(ctx,
+			`UPDATE role_change_requests SET status='pending', decided_by=NULL, decided_at=NULL WHERE id=$1`,
+			id); rbErr != nil {
+			err = fmt.Errorf("%w (and reopening request failed: %v)", err, rbErr)
+		}
+		if errors.Is(err, errLastSuperadmin) {
+			respondErr(c, http.StatusConflict, errLastSuperadmin.Error(), nil)
+			return
+		}
+		respondErr(c, http.StatusInternalServerError, "failed to apply role change", err)
+		return
+	}
+	recordAudit(ctx, confirmerID, rc.UserID, "role_change_confirmed", fmt.Sprintf("%s %s #%d", rc.Action, rc.Space, id))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Role change confirmed and applied",
+		"request_id": id,
+		"action":     rc.Action,
+		"user_id":    rc.UserID,
+		"space":      rc.Space,
+	})
+}
+
+// POST /superadmin/role-requests/:id/reject - Reject (or, as requester, withdraw) a pending request
+func rejectRoleChangeRequest(c *gin.Context) {
+	id, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request id"})
+		return
+	}
+	actorID, ok := getActorID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+
+	var userID int
+	var action, space string
+	err = conn.QueryRow(context.Background(),
+		`UPDATE role_change_requests SET status='rejected', decided_by=$2, decided_at=now()
+         WHERE id=$1 AND status='pending' AND expires_at > now()
+         RETURNING user_id, action, space`,
+		id, actorID).Scan(&userID, &action, &space)
+	if err == pgx.ErrNoRows {
+		respondErr(c, http.StatusConflict, "request not found or no longer pending", nil)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to reject request", err)
+		return
+	}
+	recordAudit(context.Background(), actorID, userID, "role_change_rejected", fmt.Sprintf("%s %s #%d", action, space, id))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role change rejected", "request_id": id})
+}
+
+// respondUnclaimableRequest explains why a request could not be confirmed.
+func respondUnclaimableRequest(c *gin.Context, id, confirmerID int) {
+	var status string
+	var requestedBy int
+	var expired bool
+	err := conn.QueryRow(context.Background(),
+		`SELECT status, requested_by, expires_at <= now() FROM role_change_requests WHERE id=$1`,
+		id).Scan(&status, &requestedBy, &expired)
+	switch {
+	case err == pgx.ErrNoRows:
+		respondErr(c, http.StatusNotFound, "role change request not found", nil)
+	case err != nil:
+		respondErr(c, http.StatusInternalServerError, "failed to load request", err)
+	case status != "pending":
+		respondErr(c, http.StatusConflict, "request is already "+status, nil)
+	case expired:
+		respondErr(c, http.StatusConflict, "request has expired", nil)
+	case requestedBy == confirmerID:
+		respondErr(c, http.StatusForbidden, "a different superadmin must confirm this request", nil)
+	default:
+		respondErr(c, http.StatusConflict, "request could not be confirmed", nil)
+	}
+}
@@ -0,0 +1,99 @@
+// handlers_roles.go
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// GET /superadmin/roles/:space?q=&limit=&offset= - Members of a role space
+// q matches the display name or the user ID. Only accessible by existing Superadmins.
+func listRoleMembers(c *gin.Context) {
+	space := c.Param("space")
+	if !isRoleSpace(space) {
+		respondErr(c, http.StatusNotFound, "unknown role space", nil)
+		return
+	}
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	q := c.Query("q")
+	ctx := context.Background()
+
+	const filter = `FROM role_grants g LEFT JOIN names n ON n.id = g.user_id
+         WHERE g.space = $1
+           AND ($2 = '' OR n.name ILIKE '%' || $2 || '%' OR g.user_id::text = $2)`
+
+	var total int
+	if err := conn.QueryRow(ctx, `SELECT count(*) `+filter, space, q).Scan(&total); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to count role members", err)
+		return
+	}
+
+	rows, err := conn.Query(ctx,
+		`SELECT g.user_id, COALESCE(n.name, ''), g.space, g.granted_at, g.expires_at `+filter+`
+         ORDER BY g.granted_at, g.user_id LIMIT $3 OFFSET $4`,
+		space, q, limit, offset)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch role members", err)
+		return
+	}
+	members, err := pgx.CollectRows(rows, pgx.RowToStructByPos[RoleGrant])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": members, "total": total, "limit": limit, "offset": offset})
+}
+
+// GET /users/:id/roles?q=&limit=&offset= - Role grants of one user
+// (also served at /superadmin/users/:id/roles). q matches the space name.
+// Users may list their own grants; anyone else's need a Superadmin.
+func listUserRoles(c *gin.Context) {
+	userID, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	callerID, ok := getUserID(c)
+	if !ok || (callerID != userID && !hasRoleFor(c, uidToStr(callerID), "superadmin")) {
+		respondErr(c, http.StatusForbidden, "you may only list your own roles", nil)
+		return
+	}
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	q := c.Query("q")
+	ctx := context.Background()
+
+	const filter = `FROM role_grants g LEFT JOIN names n ON n.id = g.user_id
+         WHERE g.user_id = $1 AND ($2 = '' OR g.space ILIKE '%' || $2 || '%')`
+
+	var total int
+	if err := conn.QueryRow(ctx, `SELECT count(*) `+filter, userID, q).Scan(&total); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to count role grants", err)
+		return
+	}
+
+	rows, err := conn.Query(ctx,
+		`SELECT g.user_id, COALESCE(n.name, ''), g.space, g.granted_at, g.expires_at `+filter+`
+         ORDER BY g.space LIMIT $3 OFFSET $4`,
+		userID, q, limit, offset)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch role grants", err)
+		return
+	}
+	grants, err := pgx.CollectRows(rows, pgx.RowToStructByPos[RoleGrant])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "items": grants, "total": total, "limit": limit, "offset": offset})
+}
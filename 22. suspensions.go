@@ -0,0 +1,241 @@
+// handlers_suspensions.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Models ---
+
+// Suspension represents a record in the 'user_suspensions' table.
+type Suspension struct {
+	ID          int64      `json:"id"`
+	UserID      int        `json:"user_id"`
+	Reason      string     `json:"reason"`
+	SuspendedBy int        `json:"suspended_by"`
+	SuspendedAt time.Time  `json:"suspended_at"`
+	Until       *time.Time `json:"until"` // nil for a permanent suspension
+	LiftedBy    *int       `json:"lifted_by"`
+	LiftedAt    *time.Time `json:"lifted_at"`
+}
+
+// suspendReq is the JSON body for suspending a user.
+type suspendReq struct {
+	Reason string     `json:"reason" binding:"required"`
+	Until  *time.Time `json:"until"` // Optional, permanent when omitted
+}
+
+// suspensionColumns matches the field order of Suspension.
+const suspensionColumns = `id, user_id, reason, suspended_by, suspended_at, until, lifted_by, lifted_at`
+
+// activeSuspensionFilter selects suspensions that are in force right now.
+const activeSuspensionFilter = `lifted_at IS NULL AND (until IS NULL OR until > now())`
+
+// --- Middleware ---
+
+// SuspensionMiddleware rejects suspended users with 403 on every protected
+// route, whatever credential they authenticated with. It runs right after
+// AuthMiddleware, so it checks the real user and not an impersonated one.
+func SuspensionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := getUserID(c)
+		if !ok {
+			c.Next()
+			return
+		}
+		s, err := activeSuspension(context.Background(), userID)
+		if err != nil {
+			respondErr(c, http.StatusInternalServerError, "failed to check account status", err)
+			c.Abort()
+			return
+		}
+		if s != nil {
+			respondSuspended(c, s)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// --- Handlers ---
+
+// POST /superadmin/users/:id/suspension - Suspend a user
+// Ends their sessions and withdraws their pending submissions, which stay
+// in buffer_projects with status 'withdrawn'.
+func suspendUser(c *gin.Context) {
+	userID, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	var req suspendReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body, reason is required"})
+		return
+	}
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "until must be in the future"})
+		return
+	}
+	actorID, ok := getActorID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+
+	// 1. Superadmins lose the role first, through the last-superadmin guard
+	if userID == actorID {
+		respondErr(c, http.StatusConflict, "cannot suspend yourself", nil)
+		return
+	}
+	if hasRoleFor(c, uidToStr(userID), "superadmin") {
+		respondErr(c, http.StatusConflict, "revoke the superadmin role before suspending this user", nil)
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "tx begin failed", err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// 2. Replace any suspension currently in force
+	if _, err := tx.Exec(ctx,
+		`UPDATE user_suspensions SET lifted_at=now(), lifted_by=$2
+         WHERE user_id=$1 AND `+activeSuspensionFilter, userID, actorID); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to replace suspension", err)
+		return
+	}
+	var s Suspension
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO user_suspensions (user_id, reason, suspended_by, until) VALUES ($1, $2, $3, $4)
+         RETURNING `+suspensionColumns,
+		userID, req.Reason, actorID, req.Until).Scan(
+		&s.ID, &s.UserID, &s.Reason, &s.SuspendedBy, &s.SuspendedAt, &s.Until, &s.LiftedBy, &s.LiftedAt); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to suspend user", err)
+		return
+	}
+
+	// 3. Withdraw pending submissions (kept for history)
+	tag, err := tx.Exec(ctx,
+		`UPDATE buffer_projects SET status='withdrawn' WHERE creator_id=$1 AND status='pending'`, userID)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to withdraw pending projects", err)
+		return
+	}
+
+	// 4. End sessions inside the same transaction
+	if _, err := tx.Exec(ctx,
+		`UPDATE auth_sessions SET revoked_at=now() WHERE user_id=$1 AND revoked_at IS NULL`, userID); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to revoke sessions", err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		respondErr(c, http.StatusInternalServerError, "commit failed", err)
+		return
+	}
+	recordAudit(ctx, actorID, userID, "user_suspended", suspensionDetail(&s))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "User suspended",
+		"suspension":         s,
+		"projects_withdrawn": tag.RowsAffected(),
+	})
+}
+
+// DELETE /superadmin/users/:id/suspension - Lift a user's suspension
+// Withdrawn submissions are not restored; the user can submit again.
+func liftSuspension(c *gin.Context) {
+	userID, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	actorID, ok := getActorID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+
+	tag, err := conn.Exec(context.Background(),
+		`UPDATE user_suspensions SET lifted_at=now(), lifted_by=$2
+         WHERE user_id=$1 AND `+activeSuspensionFilter, userID, actorID)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to lift suspension", err)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondErr(c, http.StatusNotFound, "user is not suspended", nil)
+		return
+	}
+	recordAudit(context.Background(), actorID, userID, "suspension_lifted", "")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Suspension lifted", "user_id": userID})
+}
+
+// GET /superadmin/users/:id/suspensions - A user's suspension history, newest first
+func listUserSuspensions(c *gin.Context) {
+	userID, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	rows, err := conn.Query(context.Background(),
+		`SELECT `+suspensionColumns+` FROM user_suspensions WHERE user_id=$1 ORDER BY suspended_at DESC`, userID)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch suspensions", err)
+		return
+	}
+	out, err := pgx.CollectRows(rows, pgx.RowToStructByPos[Suspension])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "items": out})
+}
+
+// --- Helpers ---
+
+// activeSuspension returns the suspension in force for userID, or nil.
+func activeSuspension(ctx context.Context, userID int) (*Suspension, error) {
+	rows, err := conn.Query(ctx,
+		`SELECT `+suspensionColumns+` FROM user_suspensions
+         WHERE user_id=$1 AND `+activeSuspensionFilter+` ORDER BY suspended_at DESC LIMIT 1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	s, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByPos[Suspension])
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// respondSuspended writes the 403 for a suspended account.
+func respondSuspended(c *gin.Context, s *Suspension) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":  "account suspended",
+		"reason": s.Reason,
+		"until":  s.Until,
+	})
+}
+
+// suspensionDetail describes a suspension for the audit log.
+func suspensionDetail(s *Suspension) string {
+	if s.Until == nil {
+		return "permanent: " + s.Reason
+	}
+	return fmt.Sprintf("until %s: %s", s.Until.Format(time.RFC3339), s.Reason)
+}
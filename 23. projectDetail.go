@@ -0,0 +1,90 @@
+// handlers_project_detail.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// GET /projects/:id - A single approved project with its creator's profile,
+// maintainers and contributors. Public; deleted projects are 404, and admins
+// additionally get a pointer to the archived copy.
+func getProjectDetail(c *gin.Context) {
+	pid, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	ctx := context.Background()
+
+	// 1. The project itself
+	var d ProjectDetail
+	d.Project, err = loadProject(ctx, conn, pid)
+	if err == pgx.ErrNoRows {
+		respondProjectMissing(c, pid)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch project", err)
+		return
+	}
+
+	// 2. Creator profile (falls back to the name stored on the project)
+	d.Creator = UserProfile{UserID: d.CreatorID, Name: d.CreatorName}
+	if err := conn.QueryRow(ctx,
+		`SELECT COALESCE((SELECT name FROM names WHERE id=$1), $2),
+                (SELECT count(*) FROM approved_projects WHERE creator_id=$1)`,
+		d.CreatorID, d.CreatorName).Scan(&d.Creator.Name, &d.Creator.ProjectsCreated); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch creator", err)
+		return
+	}
+
+	// 3. Maintainers and contributors
+	rows, err := conn.Query(ctx, `SELECT user_id, m_name FROM maintainers WHERE p_id=$1 ORDER BY m_name`, pid)
+	if err == nil {
+		d.Maintainers, err = pgx.CollectRows(rows, pgx.RowToStructByPos[Maintainer])
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch maintainers", err)
+		return
+	}
+	rows, err = conn.Query(ctx, `SELECT user_id, c_name FROM contributors WHERE p_id=$1 ORDER BY c_name`, pid)
+	if err == nil {
+		d.Contributors, err = pgx.CollectRows(rows, pgx.RowToStructByPos[Contributor])
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch contributors", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, d)
+}
+
+// respondProjectMissing writes the 404 for a project that is not approved,
+// telling admins where a deleted one was archived.
+func respondProjectMissing(c *gin.Context, pid int) {
+	userID, ok := getUserID(c)
+	if !ok || !hasRoleFor(c, uidToStr(userID), "admin") {
+		respondErr(c, http.StatusNotFound, "project not found", nil)
+		return
+	}
+
+	var deleted bool
+	if err := conn.QueryRow(context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM deleted_projects WHERE p_id=$1)`, pid).Scan(&deleted); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to check archive", err)
+		return
+	}
+	if !deleted {
+		respondErr(c, http.StatusNotFound, "project not found", nil)
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{
+		"error":   "project has been deleted",
+		"archive": fmt.Sprintf("/admin/deleted/%d", pid),
+	})
+}
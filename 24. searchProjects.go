@@ -0,0 +1,120 @@
+// handlers_search.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Models ---
+
+// SearchResult is one ranked hit of GET /projects/search.
+type SearchResult struct {
+	Source  string  `json:"source"` // approved, buffer or deleted
+	ID      int     `json:"id"`     // p_id, or r_id for buffer projects
+	Name    string  `json:"name"`
+	Snippet string  `json:"snippet"` // HTML-escaped description with <mark>ed matches
+	Status  string  `json:"status"`
+	Score   float64 `json:"score"`
+}
+
+// searchSources maps ?include= values to the SELECT searching each table.
+// $1 is the raw query, tsq the parsed one (see searchProjects).
+var searchSources = map[string]string{
+	"approved": `SELECT 'approved', p_id, name, ` + searchSnippet + `, status, ` + searchScore + `
+                 FROM approved_projects, q WHERE ` + searchMatch + searchFilter,
+	"buffer": `SELECT 'buffer', r_id, name, ` + searchSnippet + `, status, ` + searchScore + `
+               FROM buffer_projects, q WHERE ` + searchMatch + searchFilter,
+	"deleted": `SELECT 'deleted', p_id, name, ` + searchSnippet + `, 'deleted', ` + searchScore + `
+                FROM deleted_projects, q WHERE ` + searchMatch + searchFilter,
+}
+
+const (
+	// Full-text match on the weighted vector (name A, description B), or a
+	// trigram match on the name to tolerate typos.
+	searchMatch = `(search_tsv @@ q.tsq OR name % $1 OR $1 <% name)`
+	// Optional ?category=, ?tag= and ?tech= ($4 to $6), as on GET /all.
+	searchFilter = ` AND ($4::text IS NULL OR category = $4)
+                     AND ($5::text[] IS NULL OR tags @> $5) AND ($6::text[] IS NULL OR tech_stack @> $6)`
+	// Text rank plus name similarity, so typo-only hits still sort sensibly.
+	searchScore = `(ts_rank(search_tsv, q.tsq) + 0.5 * word_similarity($1, name))::float8`
+	// The description is HTML-escaped first, so <mark> is the only markup in
+	// the snippet and clients can render it as HTML.
+	searchSnippet = `ts_headline('english', ` + searchEscapedDescription + `, q.tsq,
+                         'StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2')`
+	searchEscapedDescription = `replace(replace(replace(replace(replace(COALESCE(description, ''),
+                                    '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+)
+
+// --- Handlers ---
+
+// GET /projects/search?q=&include=&limit=&offset= - Keyword search, best matches first
+// Covers approved projects; admins may add include=buffer,deleted (comma
+// separated) to also search submissions and the archive. Results can be
+// narrowed with category, tag and tech as on GET /all.
+func searchProjects(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" || len(q) > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required (at most 200 characters)"})
+		return
+	}
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	var category *string
+	if s := c.Query("category"); s != "" {
+		category = &s
+	}
+	tags, tech, ok := labelQuery(c)
+	if !ok {
+		return
+	}
+
+	// 1. Which tables to search
+	sources := []string{"approved"}
+	if inc := c.Query("include"); inc != "" {
+		userID, ok := getUserID(c)
+		if !ok || !hasRoleFor(c, uidToStr(userID), "admin") {
+			respondErr(c, http.StatusForbidden, "only admins may include buffer or deleted projects", nil)
+			return
+		}
+		for _, s := range strings.Split(inc, ",") {
+			s = strings.TrimSpace(s)
+			if _, known := searchSources[s]; !known {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown include %q, use buffer or deleted", s)})
+				return
+			}
+			if s != "approved" {
+				sources = append(sources, s)
+			}
+		}
+	}
+	parts := make([]string, 0, len(sources))
+	for _, s := range sources {
+		parts = append(parts, searchSources[s])
+	}
+
+	// 2. Rank across all of them
+	rows, err := conn.Query(context.Background(),
+		`WITH q AS (SELECT websearch_to_tsquery('english', $1) AS tsq) `+
+			strings.Join(parts, " UNION ALL ")+
+			` ORDER BY 6 DESC, 2 LIMIT $2 OFFSET $3`,
+		q, limit, offset, category, tags, tech)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "search failed", err)
+		return
+	}
+	out, err := pgx.CollectRows(rows, pgx.RowToStructByPos[SearchResult])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"query": q, "items": out, "limit": limit, "offset": offset})
+}
@@ -0,0 +1,404 @@
+// handlers_project_edit.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// editTrivialChars is how many characters a non-admin may change without
+// review when PF_PROJECT_EDIT_REVIEW=1 (PF_PROJECT_EDIT_TRIVIAL_CHARS),
+// counted from the last reviewed revision.
+var editTrivialChars = envInt("PF_PROJECT_EDIT_TRIVIAL_CHARS", 20)
+
+// --- Models ---
+
+// projectFields are the editable fields of an approved project. Nil means
+// "leave unchanged"; an empty category removes it.
+type projectFields struct {
+	Name        *string   `json:"name,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Category    *string   `json:"category,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"`
+	TechStack   *[]string `json:"tech_stack,omitempty"`
+}
+
+// editProjectReq is the JSON body for PATCH /projects/:id.
+type editProjectReq struct {
+	projectFields
+	Note string `json:"note"` // why the change was made
+}
+
+// ProjectEditRequest represents a record in the 'project_edit_requests' table.
+type ProjectEditRequest struct {
+	ID          int64         `json:"id"`
+	PID         int           `json:"p_id"`
+	Changes     projectFields `json:"changes"`
+	Note        string        `json:"note"`
+	RequestedBy int           `json:"requested_by"`
+	RequestedAt time.Time     `json:"requested_at"`
+	Status      string        `json:"status"`
+	DecidedBy   *int          `json:"decided_by"`
+	DecidedAt   *time.Time    `json:"decided_at"`
+}
+
+// --- Handlers ---
+
+// PATCH /projects/:id - Edit an approved project's name, description,
+// category, tags and/or tech stack.
+// Creator, maintainers, admins and superadmins (see projectPolicy). With
+// PF_PROJECT_EDIT_REVIEW=1, larger edits by non-admins wait for an admin.
+func editProject(c *gin.Context) {
+	// 1. Get project ID and the changes
+	pid, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	var req editProjectReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Category != nil && !checkCategory(c, *req.Category) {
+		return
+	}
+
+	// 2. Check permissions
+	if !authorizeProject(c, ActionEditProject, pid) {
+		return
+	}
+	actorID, _ := getActorID(c) // recorded as the author, also when impersonating
+	ctx := context.Background()
+
+	// 3. Non-trivial edits by non-admins may have to be reviewed
+	reviewRequired := editReviewRequired(c)
+	if reviewRequired && holdEditForReview(c, pid, req.projectFields, req.Note) {
+		return
+	}
+
+	// 4. Apply
+	p, err := applyProjectEdit(ctx, pid, req.projectFields, actorID, req.Note, !reviewRequired)
+	if err == errProjectNotFound {
+		respondErr(c, http.StatusNotFound, "project not found", nil)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to update project", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// GET /admin/edits - Project edits waiting for review, oldest first
+func getPendingEdits(c *gin.Context) {
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	rows, err := conn.Query(context.Background(),
+		`SELECT id, p_id, changes, note, requested_by, requested_at, status, decided_by, decided_at
+         FROM project_edit_requests WHERE status='pending'
+         ORDER BY requested_at, id LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch pending edits", err)
+		return
+	}
+	out, err := pgx.CollectRows(rows, pgx.RowToStructByPos[ProjectEditRequest])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": out, "limit": limit, "offset": offset})
+}
+
+// POST /admin/edits/:id/approve - Apply a reviewed edit
+func approveEdit(c *gin.Context) {
+	id, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid edit id"})
+		return
+	}
+	adminID, _ := getActorID(c)
+	ctx := context.Background()
+
+	// 1. Claim the request so it is applied once
+	var e ProjectEditRequest
+	err = conn.QueryRow(ctx,
+		`UPDATE project_edit_requests SET status='approved', decided_by=$2, decided_at=now()
+         WHERE id=$1 AND status='pending'
+         RETURNING p_id, changes, note, requested_by`,
+		id, adminID).Scan(&e.PID, &e.Changes, &e.Note, &e.RequestedBy)
+	if err == pgx.ErrNoRows {
+		respondErr(c, http.StatusNotFound, "edit not found or not pending", nil)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to approve edit", err)
+		return
+	}
+
+	// 2. Apply it in the requester's name; the project may be gone by now
+	p, err := applyProjectEdit(ctx, e.PID, e.Changes, e.RequestedBy, e.Note, true)
+	if err != nil {
+		// An edit that can never apply is rejected; anything else may be
+		// retried, so the edit goes back to pending.
+		var pgErr *pgconn.PgError
+		status, msg := http.StatusInternalServerError, "failed to apply edit"
+		if err == errProjectNotFound {
+			status, msg = http.StatusConflict, "project no longer exists"
+		} else if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			status, msg = http.StatusConflict, "category no longer exists"
+		}
+		if status == http.StatusConflict {
+			if _, rbErr := conn.Exec(ctx,
+				`UPDATE project_edit_requests SET status='rejected', decided_by=$2, decided_at=now() WHERE id=$1`,
+				id, adminID); rbErr != nil {
+				err = fmt.Errorf("%w (and closing edit failed: %v)", err, rbErr)
+			}
+		} else if _, rbErr := conn.Exec(ctx,
+			`UPDATE project_edit_requests SET status='pending', decided_by=NULL, decided_at=NULL WHERE id=$1`,
+			id); rbErr != nil {
+			err = fmt.Errorf("%w (and reopening edit failed: %v)", err, rbErr)
+		}
+		respondErr(c, status, msg, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "approved", "project": p})
+}
+
+// POST /admin/edits/:id/reject - Reject a pending edit
+func rejectEdit(c *gin.Context) {
+	id, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid edit id"})
+		return
+	}
+	adminID, _ := getActorID(c)
+
+	cmdTag, err := conn.Exec(context.Background(),
+		`UPDATE project_edit_requests SET status='rejected', decided_by=$2, decided_at=now()
+         WHERE id=$1 AND status='pending'`, id, adminID)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "reject failed", err)
+		return
+	}
+	if cmdTag.RowsAffected() == 0 {
+		respondErr(c, http.StatusNotFound, "edit not found or not pending", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "rejected"})
+}
+
+// --- Helpers ---
+
+// validate trims the fields and checks their lengths.
+func (r *editProjectReq) validate() error {
+	if r.Name == nil && r.Description == nil && r.Category == nil && r.Tags == nil && r.TechStack == nil {
+		return errors.New("nothing to change, send name, description, category, tags and/or tech_stack")
+	}
+	if r.Name != nil {
+		*r.Name = strings.TrimSpace(*r.Name)
+		if n := utf8.RuneCountInString(*r.Name); n < 3 || n > 120 {
+			return errors.New("name must be 3 to 120 characters")
+		}
+	}
+	if r.Description != nil {
+		*r.Description = strings.TrimSpace(*r.Description)
+		if n := utf8.RuneCountInString(*r.Description); n < 10 || n > 5000 {
+			return errors.New("description must be 10 to 5000 characters")
+		}
+	}
+	if r.Category != nil {
+		*r.Category = strings.TrimSpace(*r.Category)
+	}
+	if r.Tags != nil {
+		tags, err := normalizeLabels("tags", *r.Tags, maxProjectTags)
+		if err != nil {
+			return err
+		}
+		r.Tags = &tags
+	}
+	if r.TechStack != nil {
+		tech, err := normalizeLabels("tech_stack", *r.TechStack, maxProjectTechStack)
+		if err != nil {
+			return err
+		}
+		r.TechStack = &tech
+	}
+	if utf8.RuneCountInString(r.Note) > 500 {
+		return errors.New("note must be at most 500 characters")
+	}
+	return nil
+}
+
+// applyProjectEdit updates the given fields of approved project pid for
+// authorID, records a revision if anything changed, and returns the result
+// (errProjectNotFound if the project does not exist). reviewed marks edits
+// made or approved by an admin, or applied while review is off.
+func applyProjectEdit(ctx context.Context, pid int, f projectFields, authorID int, note string, reviewed bool) (Project, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return Project{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the row so concurrent edits get consecutive revisions
+	tag, err := tx.Exec(ctx, `SELECT 1 FROM approved_projects WHERE p_id=$1 FOR UPDATE`, pid)
+	if err != nil {
+		return Project{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return Project{}, errProjectNotFound
+	}
+	before, err := loadProject(ctx, tx, pid)
+	if err != nil {
+		return Project{}, err
+	}
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE approved_projects SET name=COALESCE($2, name), description=COALESCE($3, description),
+                category=CASE WHEN $4::text IS NULL THEN category ELSE NULLIF($4, '') END,
+                tags=COALESCE($5, tags), tech_stack=COALESCE($6, tech_stack)
+         WHERE p_id=$1`, pid, f.Name, f.Description, f.Category, f.Tags, f.TechStack); err != nil {
+		return Project{}, err
+	}
+	after, err := loadProject(ctx, tx, pid)
+	if err != nil {
+		return Project{}, err
+	}
+
+	if !reflect.DeepEqual(snapshotFields(before), snapshotFields(after)) {
+		if err := recordRevision(ctx, tx, before, after, authorID, note, reviewed); err != nil {
+			return Project{}, err
+		}
+	}
+	return after, tx.Commit(ctx)
+}
+
+// querier is satisfied by both the connection pool and a pgx.Tx.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// loadProject reads approved project pid.
+func loadProject(ctx context.Context, db querier, pid int) (Project, error) {
+	var p Project
+	err := db.QueryRow(ctx,
+		`SELECT `+projectColumns+` FROM approved_projects WHERE p_id=$1`, pid).Scan(
+		&p.PID, &p.Name, &p.Description, &p.CreatorID, &p.CreatorName, &p.StartDate, &p.Status,
+		&p.Category, &p.Tags, &p.TechStack, &p.EndDate, &p.OverdueSince)
+	return p, err
+}
+
+// editReviewRequired reports whether the user's larger edits wait for an
+// admin: PF_PROJECT_EDIT_REVIEW=1 and the user is not an admin.
+func editReviewRequired(c *gin.Context) bool {
+	userID, _ := getUserID(c)
+	return os.Getenv("PF_PROJECT_EDIT_REVIEW") == "1" && !hasRoleFor(c, uidToStr(userID), "admin")
+}
+
+// holdEditForReview files f as a pending edit and answers 202 if it is too
+// large to apply without review (see exceedsTrivialEdit). It returns whether
+// a response was written.
+func holdEditForReview(c *gin.Context, pid int, f projectFields, note string) bool {
+	ctx := context.Background()
+	tooLarge, err := exceedsTrivialEdit(ctx, pid, f)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to load project", err)
+		return true
+	}
+	if !tooLarge {
+		return false
+	}
+
+	actorID, _ := getActorID(c)
+	var id int64
+	if err := conn.QueryRow(ctx,
+		`INSERT INTO project_edit_requests (p_id, changes, note, requested_by)
+         VALUES ($1, $2, $3, $4) RETURNING id`,
+		pid, f, note, actorID).Scan(&id); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to submit edit for review", err)
+		return true
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Edit submitted for review", "edit_id": id, "status": "pending"})
+	return true
+}
+
+// exceedsTrivialEdit reports whether applying f to project pid would change
+// more than editTrivialChars since the last reviewed revision, so a large
+// edit cannot slip through as a series of small ones.
+func exceedsTrivialEdit(ctx context.Context, pid int, f projectFields) (bool, error) {
+	current, err := loadProject(ctx, conn, pid)
+	if err != nil {
+		return false, err
+	}
+
+	// Without reviewed revisions the project is as approved
+	base := current
+	var fields projectFields
+	err = conn.QueryRow(ctx,
+		`SELECT fields FROM project_revisions WHERE p_id=$1 AND reviewed ORDER BY rev DESC LIMIT 1`,
+		pid).Scan(&fields)
+	if err != nil && err != pgx.ErrNoRows {
+		return false, err
+	}
+	if err == nil && fields.Name != nil && fields.Description != nil {
+		base.Name, base.Description = *fields.Name, *fields.Description
+	}
+
+	after := snapshotFields(current)
+	if f.Name != nil {
+		after.Name = f.Name
+	}
+	if f.Description != nil {
+		after.Description = f.Description
+	}
+	return changedChars(base, after) > editTrivialChars, nil
+}
+
+// changedChars estimates how many characters f changes in p: per field, the
+// length of the changed middle once the common prefix and suffix are removed.
+// Category, tags and tech stack do not count.
+func changedChars(p Project, f projectFields) int {
+	n := 0
+	if f.Name != nil {
+		n += changedMiddle(p.Name, *f.Name)
+	}
+	if f.Description != nil {
+		n += changedMiddle(p.Description, *f.Description)
+	}
+	return n
+}
+
+func changedMiddle(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	pre := 0
+	for pre < len(ra) && pre < len(rb) && ra[pre] == rb[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(ra)-pre && suf < len(rb)-pre && ra[len(ra)-1-suf] == rb[len(rb)-1-suf] {
+		suf++
+	}
+	return max(len(ra), len(rb)) - pre - suf
+}
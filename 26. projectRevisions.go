@@ -0,0 +1,258 @@
+// handlers_project_revisions.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// --- Models ---
+
+// ProjectRevision represents a record in the 'project_revisions' table: the
+// editable fields of a project after a change. Revision 1 is the project as
+// it was before its first edit.
+type ProjectRevision struct {
+	PID       int           `json:"p_id"`
+	Rev       int           `json:"rev"`
+	Fields    projectFields `json:"fields"`
+	AuthorID  *int          `json:"author_id"` // nil for revision 1
+	Note      string        `json:"note"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// FieldChange is one line of a revision diff.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// revertReq is the optional JSON body for reverting to a revision.
+type revertReq struct {
+	Note string `json:"note"`
+}
+
+// --- Handlers ---
+
+// GET /projects/:id/revisions - A project's revisions, newest first
+func getProjectRevisions(c *gin.Context) {
+	pid, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	rows, err := conn.Query(context.Background(),
+		`SELECT p_id, rev, fields, author_id, note, created_at FROM project_revisions
+         WHERE p_id=$1 ORDER BY rev DESC`, pid)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch revisions", err)
+		return
+	}
+	out, err := pgx.CollectRows(rows, pgx.RowToStructByPos[ProjectRevision])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"p_id": pid, "items": out})
+}
+
+// GET /projects/:id/revisions/diff?from=&to= - Field-level diff between two revisions
+func diffProjectRevisions(c *gin.Context) {
+	pid, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	from, ok1 := queryInt(c, "from")
+	if !ok1 {
+		return
+	}
+	to, ok2 := queryInt(c, "to")
+	if !ok2 {
+		return
+	}
+	if from == nil || to == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to revisions are required"})
+		return
+	}
+
+	var a, b ProjectRevision
+	a, err = loadRevision(context.Background(), pid, *from)
+	if err == nil {
+		b, err = loadRevision(context.Background(), pid, *to)
+	}
+	if err == pgx.ErrNoRows {
+		respondErr(c, http.StatusNotFound, "revision not found", nil)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to load revision", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"p_id": pid, "from": *from, "to": *to, "changes": diffFields(a.Fields, b.Fields)})
+}
+
+// POST /projects/:id/revisions/:rev/revert - Restore a revision's fields.
+// The revert is itself a new revision, so nothing is lost. Like any other
+// edit it may have to wait for review (see editProject).
+// Maintainers, admins and superadmins (see projectPolicy).
+func revertProjectRevision(c *gin.Context) {
+	// 1. Get project and revision
+	pid, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	rev, err := getIntParam(c, "rev")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return
+	}
+	var req revertReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+
+	// 2. Check permissions
+	if !authorizeProject(c, ActionRevertProject, pid) {
+		return
+	}
+	actorID, _ := getActorID(c)
+	ctx := context.Background()
+
+	// 3. Load the old fields; their category may have been deleted since
+	r, err := loadRevision(ctx, pid, rev)
+	if err == pgx.ErrNoRows {
+		respondErr(c, http.StatusNotFound, "revision not found", nil)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to load revision", err)
+		return
+	}
+	if r.Fields.Category != nil && *r.Fields.Category != "" {
+		exists, err := categoryExists(ctx, *r.Fields.Category)
+		if err != nil {
+			respondErr(c, http.StatusInternalServerError, "failed to check category", err)
+			return
+		}
+		if !exists {
+			respondErr(c, http.StatusConflict, fmt.Sprintf("category %q of revision %d no longer exists", *r.Fields.Category, rev), nil)
+			return
+		}
+	}
+	note := fmt.Sprintf("revert to revision %d", rev)
+	if req.Note != "" {
+		note += ": " + req.Note
+	}
+
+	// 4. Apply them as a new edit, unless it has to be reviewed first
+	reviewRequired := editReviewRequired(c)
+	if reviewRequired && holdEditForReview(c, pid, r.Fields, note) {
+		return
+	}
+	p, err := applyProjectEdit(ctx, pid, r.Fields, actorID, note, !reviewRequired)
+	if err == errProjectNotFound {
+		respondErr(c, http.StatusNotFound, "project not found", nil)
+		return
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		respondErr(c, http.StatusConflict, "category no longer exists", err)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to revert project", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// --- Helpers ---
+
+// recordRevision stores the change from before to after in tx. The first
+// change of a project also stores revision 1, the state before it. The
+// caller must hold the project's row lock so revision numbers do not race.
+func recordRevision(ctx context.Context, tx pgx.Tx, before, after Project, authorID int, note string, reviewed bool) error {
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO project_revisions (p_id, rev, fields, note)
+         SELECT $1, 1, $2, 'original' WHERE NOT EXISTS (SELECT 1 FROM project_revisions WHERE p_id=$1)`,
+		before.PID, snapshotFields(before)); err != nil {
+		return fmt.Errorf("record original revision: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO project_revisions (p_id, rev, fields, author_id, note, reviewed)
+         SELECT $1, MAX(rev) + 1, $2, $3, $4, $5 FROM project_revisions WHERE p_id=$1`,
+		after.PID, snapshotFields(after), authorID, note, reviewed); err != nil {
+		return fmt.Errorf("record revision: %w", err)
+	}
+	return nil
+}
+
+// snapshotFields returns every editable field of p.
+func snapshotFields(p Project) projectFields {
+	category := ""
+	if p.Category != nil {
+		category = *p.Category
+	}
+	return projectFields{Name: &p.Name, Description: &p.Description, Category: &category,
+		Tags: &p.Tags, TechStack: &p.TechStack}
+}
+
+// loadRevision reads revision rev of project pid.
+func loadRevision(ctx context.Context, pid, rev int) (ProjectRevision, error) {
+	rows, err := conn.Query(ctx,
+		`SELECT p_id, rev, fields, author_id, note, created_at FROM project_revisions
+         WHERE p_id=$1 AND rev=$2`, pid, rev)
+	if err != nil {
+		return ProjectRevision{}, err
+	}
+	return pgx.CollectOneRow(rows, pgx.RowToStructByPos[ProjectRevision])
+}
+
+// diffFields lists the fields whose values differ between a and b, by JSON name.
+func diffFields(a, b projectFields) []FieldChange {
+	am, bm := fieldMap(a), fieldMap(b)
+	names := make([]string, 0, len(am)+len(bm))
+	for k := range am {
+		names = append(names, k)
+	}
+	for k := range bm {
+		if _, ok := am[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	out := []FieldChange{}
+	for _, k := range names {
+		if !reflect.DeepEqual(am[k], bm[k]) {
+			out = append(out, FieldChange{Field: k, From: am[k], To: bm[k]})
+		}
+	}
+	return out
+}
+
+func fieldMap(f projectFields) map[string]any {
+	var m map[string]any
+	b, _ := json.Marshal(f)
+	_ = json.Unmarshal(b, &m)
+	return m
+}
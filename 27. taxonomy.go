@@ -0,0 +1,254 @@
+// handlers_taxonomy.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Limits for the free-form labels on a project.
+const (
+	maxProjectTags      = 20
+	maxProjectTechStack = 20
+)
+
+// labelPattern is what a tag or tech stack entry looks like after
+// normalization, e.g. "go", "c++", "c#", "node.js", "machine-learning".
+var labelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9+#.\-]{0,31}$`)
+
+// categorySlugPattern is the shape of a category slug.
+var categorySlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9\-]{0,39}$`)
+
+// --- Models ---
+
+// Category represents a record in the 'categories' table, with the number
+// of approved projects filed under it.
+type Category struct {
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Projects  int       `json:"projects"`
+}
+
+// createCategoryReq is the JSON body for POST /admin/categories.
+type createCategoryReq struct {
+	Slug string `json:"slug" binding:"required"`
+	Name string `json:"name" binding:"required"`
+}
+
+// LabelCount is one entry of the tag cloud.
+type LabelCount struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// --- Handlers ---
+
+// GET /categories - All categories with their approved project counts
+func listCategories(c *gin.Context) {
+	rows, err := conn.Query(context.Background(),
+		`SELECT cat.slug, cat.name, cat.created_at,
+                (SELECT count(*) FROM approved_projects p WHERE p.category=cat.slug)::int
+         FROM categories cat ORDER BY cat.name`)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch categories", err)
+		return
+	}
+	out, err := pgx.CollectRows(rows, pgx.RowToStructByPos[Category])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+// POST /admin/categories - Add a category
+func createCategory(c *gin.Context) {
+	var req createCategoryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	req.Slug = strings.ToLower(strings.TrimSpace(req.Slug))
+	req.Name = strings.TrimSpace(req.Name)
+	if !categorySlugPattern.MatchString(req.Slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug must be 1 to 40 lowercase letters, digits or dashes"})
+		return
+	}
+	if req.Name == "" || len(req.Name) > 80 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1 to 80 characters"})
+		return
+	}
+
+	var cat Category
+	err := conn.QueryRow(context.Background(),
+		`INSERT INTO categories (slug, name) VALUES ($1, $2)
+         ON CONFLICT (slug) DO NOTHING RETURNING slug, name, created_at`,
+		req.Slug, req.Name).Scan(&cat.Slug, &cat.Name, &cat.CreatedAt)
+	if err == pgx.ErrNoRows {
+		respondErr(c, http.StatusConflict, "category already exists", nil)
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to create category", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, cat)
+}
+
+// DELETE /admin/categories/:slug - Remove a category; its projects become
+// uncategorized.
+func deleteCategory(c *gin.Context) {
+	cmdTag, err := conn.Exec(context.Background(), `DELETE FROM categories WHERE slug=$1`, c.Param("slug"))
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to delete category", err)
+		return
+	}
+	if cmdTag.RowsAffected() == 0 {
+		respondErr(c, http.StatusNotFound, "category not found", nil)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GET /tags?category=&limit= - Tag cloud: how many approved projects carry
+// each tag and each tech stack entry, most used first.
+func getTagCloud(c *gin.Context) {
+	limit, ok := limitParam(c)
+	if !ok {
+		return
+	}
+	var category *string
+	if s := c.Query("category"); s != "" {
+		category = &s
+	}
+
+	out := gin.H{}
+	for _, column := range []string{"tags", "tech_stack"} {
+		rows, err := conn.Query(context.Background(),
+			`SELECT label, count(*)::int FROM approved_projects, unnest(`+column+`) AS label
+             WHERE $1::text IS NULL OR category=$1
+             GROUP BY label ORDER BY 2 DESC, label LIMIT $2`, category, limit)
+		if err != nil {
+			respondErr(c, http.StatusInternalServerError, "failed to count "+column, err)
+			return
+		}
+		counts, err := pgx.CollectRows(rows, pgx.RowToStructByPos[LabelCount])
+		if err != nil {
+			respondErr(c, http.StatusInternalServerError, "scan failed", err)
+			return
+		}
+		out[column] = counts
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+// --- Helpers ---
+
+// normalizeLabels lowercases, trims and de-duplicates tags or tech stack
+// entries (keeping their order), rejecting malformed ones and more than max.
+// It never returns nil, as the columns are NOT NULL.
+func normalizeLabels(field string, in []string, max int) ([]string, error) {
+	out := make([]string, 0, len(in))
+	seen := make(map[string]bool, len(in))
+	for _, l := range in {
+		l = strings.ToLower(strings.TrimSpace(l))
+		if l == "" || seen[l] {
+			continue
+		}
+		if !labelPattern.MatchString(l) {
+			return nil, fmt.Errorf("invalid %s entry %q: use up to 32 letters, digits or + # . -", field, l)
+		}
+		seen[l] = true
+		out = append(out, l)
+	}
+	if len(out) > max {
+		return nil, fmt.Errorf("at most %d %s entries allowed", max, field)
+	}
+	return out, nil
+}
+
+// checkCategory writes a 400 if slug is set but not a known category.
+func checkCategory(c *gin.Context, slug string) bool {
+	if slug == "" {
+		return true
+	}
+	exists, err := categoryExists(context.Background(), slug)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to check category", err)
+		return false
+	}
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown category %q, see GET /categories", slug)})
+		return false
+	}
+	return true
+}
+
+// categoryExists reports whether slug is a known category.
+func categoryExists(ctx context.Context, slug string) (bool, error) {
+	var exists bool
+	err := conn.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM categories WHERE slug=$1)`, slug).Scan(&exists)
+	return exists, err
+}
+
+// validateTaxonomy normalizes the category, tags and tech stack of a new
+// project, writing the 400 itself.
+func (r *createProjectReq) validateTaxonomy(c *gin.Context) bool {
+	var err error
+	r.Category = strings.TrimSpace(r.Category)
+	if r.Tags, err = normalizeLabels("tags", r.Tags, maxProjectTags); err == nil {
+		r.TechStack, err = normalizeLabels("tech_stack", r.TechStack, maxProjectTechStack)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return checkCategory(c, r.Category)
+}
+
+// addTaxonomyFilters adds ?category=, ?tag= and ?tech= (comma separated;
+// a project must carry all of them) for tables with the taxonomy columns.
+func addTaxonomyFilters(c *gin.Context, f *listFilter) bool {
+	if s := c.Query("category"); s != "" {
+		f.add("category = $?", s)
+	}
+	tags, tech, ok := labelQuery(c)
+	if !ok {
+		return false
+	}
+	if len(tags) > 0 {
+		f.add("tags @> $?", tags)
+	}
+	if len(tech) > 0 {
+		f.add("tech_stack @> $?", tech)
+	}
+	return true
+}
+
+// labelQuery reads ?tag= and ?tech= as normalized label lists, writing the 400 itself.
+func labelQuery(c *gin.Context) (tags, tech []string, ok bool) {
+	var err error
+	if v := c.Query("tag"); v != "" {
+		tags, err = normalizeLabels("tag", strings.Split(v, ","), maxProjectTags)
+	}
+	if v := c.Query("tech"); v != "" && err == nil {
+		tech, err = normalizeLabels("tech", strings.Split(v, ","), maxProjectTechStack)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	return tags, tech, true
+}
@@ -6,7 +6,6 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
@@ -24,59 +23,26 @@ type addMaintainerReq struct {
 // addMaintainer adds a user as a maintainer for a specific project.
 // Access: SuperAdmin, Admin, or Project Creator
 func addMaintainer(c *gin.Context) {
-	// 1. Get Authenticated User
-	authedUserID, ok := getUserID(c)
-	if !ok {
-		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
-		return
-	}
-
-	// 2. Get Project ID from URL
+	// 1. Get Project ID from URL
 	p_id, err := getIntParam(c, "id")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
 		return
 	}
 
-	// 3. Bind Request Body
+	// 2. Bind Request Body
 	var req addMaintainerReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body, user_id and user_name are required"})
 		return
 	}
 
-	// 4. --- Permission Check ---
-	authedUserIDStr := uidToStr(authedUserID)
-	isSuperAdmin := HasRole(authedUserIDStr, "superadmin")
-	isAdmin := HasRole(authedUserIDStr, "admin")
-	isCreator := false
-
-	// If not a global admin, check if they are the project creator
-	if !isSuperAdmin && !isAdmin {
-		var creatorID int
-		err := conn.QueryRow(context.Background(),
-			"SELECT creator_id FROM approved_projects WHERE p_id = $1", p_id).Scan(&creatorID)
-
-		if err == pgx.ErrNoRows {
-			respondErr(c, http.StatusNotFound, "project not found", nil)
-			return
-		}
-		if err != nil {
-			respondErr(c, http.StatusInternalServerError, "failed to check project ownership", err)
-			return
-		}
-		if creatorID == authedUserID {
-			isCreator = true
-		}
-	}
-
-	// 5. Enforce Permission
-	if !isSuperAdmin && !isAdmin && !isCreator {
-		respondErr(c, http.StatusForbidden, "user is not authorized to add maintainers", nil)
+	// 3. --- Permission Check (SuperAdmin, Admin or Creator; see projectPolicy) ---
+	if !authorizeProject(c, ActionAddMaintainer, p_id) {
 		return
 	}
 
-	// 6. --- Permission Granted: Perform Database Actions ---
+	// 4. --- Permission Granted: Perform Database Actions ---
 	
 	tx, err := conn.Begin(context.Background())
 	if err != nil {
@@ -4,6 +4,7 @@ package main
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,34 +15,57 @@ import (
 
 // DeletedProject represents a record in the 'deleted_projects' table.
 type DeletedProject struct {
-	PID          int       `json:"p_id"`
-	Name         string    `json:"name"`
-	Description  string    `json:"description"`
-	CreatorID    int       `json:"creator_id"`
-	CreatorName  string    `json:"creator_name"`
-	DeletedDate  time.Time `json:"deleted_date"`
+	PID         int       `json:"p_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatorID   int       `json:"creator_id"`
+	CreatorName string    `json:"creator_name"`
+	DeletedDate time.Time `json:"deleted_date"`
+	Category    *string   `json:"category"`
+	Tags        []string  `json:"tags"`
+	TechStack   []string  `json:"tech_stack"`
+	// Snapshot for restoring; nil/empty for projects archived before it existed
+	Status       *string       `json:"status"`
+	StartDate    *time.Time    `json:"start_date"`
+	EndDate      *time.Time    `json:"end_date"`
+	Maintainers  []Maintainer  `json:"maintainers"`
+	Contributors []Contributor `json:"contributors"`
+	DeletedBy    *int          `json:"deleted_by"`
+	LegalHold    bool          `json:"legal_hold"` // exempt from purging (see retention.go)
 }
 
+// deletedColumns selects a DeletedProject.
+const deletedColumns = `p_id, name, description, creator_id, creator_name, deleted_date, category, tags, tech_stack,
+	status, start_date, end_date, maintainers, contributors, deleted_by, legal_hold`
+
 // --- Handlers ---
 
-// getAllDeletedProjects handles viewing all deleted projects.
+// deletedListSpec backs GET /admin/deleted; most recently deleted first.
+var deletedListSpec = listSpec[DeletedProject]{
+	from:     "deleted_projects",
+	columns:  deletedColumns,
+	idColumn: "p_id",
+	id:       func(p DeletedProject) int { return p.PID },
+	sorts: map[string]sortField[DeletedProject]{
+		"p_id":         {"p_id", "int", func(p DeletedProject) string { return strconv.Itoa(p.PID) }},
+		"name":         {"name", "text", func(p DeletedProject) string { return p.Name }},
+		"deleted_date": {"deleted_date", "timestamptz", func(p DeletedProject) string { return p.DeletedDate.Format(timeKeyLayout) }},
+	},
+	defaultSort: "deleted_date",
+	defaultDesc: true,
+}
+
+// GET /admin/deleted - getAllDeletedProjects handles viewing all deleted projects.
+// Filters: creator_id, name_prefix, category, tag, tech, deleted_from, deleted_to (YYYY-MM-DD).
+// Paging: sort (p_id|name|deleted_date), order, limit, cursor.
 // Access: Admin, SuperAdmin
 func getAllDeletedProjects(c *gin.Context) {
-	rows, err := conn.Query(context.Background(),
-		"SELECT p_id, name, description, creator_id, creator_name, deleted_date FROM deleted_projects")
-	if err != nil {
-		respondErr(c, http.StatusInternalServerError, "failed to fetch deleted projects", err)
-		return
-	}
-	defer rows.Close()
-
-	var out []DeletedProject
-	if err = pgx.AssignRows(rows, &out); err != nil {
-		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+	var f listFilter
+	if !addCommonFilters(c, &f) || !addTaxonomyFilters(c, &f) ||
+		!addDateRange(c, &f, "deleted_date", "deleted_from", "deleted_to") {
 		return
 	}
-
-	c.JSON(http.StatusOK, out)
+	listPage(c, deletedListSpec, &f)
 }
 
 // getMyDeletedProjects handles viewing the user's own deleted projects.
@@ -55,18 +79,133 @@ func getMyDeletedProjects(c *gin.Context) {
 	}
 
 	rows, err := conn.Query(context.Background(),
-		"SELECT p_id, name, description, creator_id, creator_name, deleted_date FROM deleted_projects WHERE creator_id=$1", creatorID)
+		"SELECT "+deletedColumns+" FROM deleted_projects WHERE creator_id=$1", creatorID)
 	if err != nil {
 		respondErr(c, http.StatusInternalServerError, "failed to fetch your deleted projects", err)
 		return
 	}
-	defer rows.Close()
+	out, err := pgx.CollectRows(rows, pgx.RowToStructByPos[DeletedProject])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+// GET /admin/deleted/:id - getDeletedProject shows one archived project.
+// Access: Admin, SuperAdmin
+func getDeletedProject(c *gin.Context) {
+	pid, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
 
-	var out []DeletedProject
-	if err = pgx.AssignRows(rows, &out); err != nil {
+	rows, err := conn.Query(context.Background(),
+		"SELECT "+deletedColumns+" FROM deleted_projects WHERE p_id=$1", pid)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch deleted project", err)
+		return
+	}
+	out, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[DeletedProject])
+	if err == pgx.ErrNoRows {
+		respondErr(c, http.StatusNotFound, "deleted project not found", nil)
+		return
+	}
+	if err != nil {
 		respondErr(c, http.StatusInternalServerError, "scan failed", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, out)
 }
+
+// POST /admin/deleted/:id/restore - restoreDeletedProject brings an archived
+// project back under its original p_id, with its maintainers and contributors,
+// in one transaction. A category deleted in the meantime is dropped.
+// Access: Admin, SuperAdmin
+func restoreDeletedProject(c *gin.Context) {
+	pid, err := getIntParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	adminID, _ := getActorID(c)
+	ctx := context.Background()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "tx begin failed", err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Recreate the project (listing columns keeps generated ones like search_tsv out)
+	var status string
+	err = tx.QueryRow(ctx,
+		`INSERT INTO approved_projects (p_id, name, description, creator_id, creator_name, start_date, end_date, status,
+                                        category, tags, tech_stack)
+         SELECT p_id, name, description, creator_id, creator_name, COALESCE(start_date, deleted_date::date), end_date,
+                COALESCE(status, $2), (SELECT slug FROM categories WHERE slug=d.category), tags, tech_stack
+         FROM deleted_projects d WHERE p_id=$1
+         ON CONFLICT (p_id) DO NOTHING RETURNING status`, pid, StatusUpcoming).Scan(&status)
+	if err == pgx.ErrNoRows {
+		// Either not archived, or the p_id is taken again
+		var archived bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM deleted_projects WHERE p_id=$1)`, pid).Scan(&archived); err != nil {
+			respondErr(c, http.StatusInternalServerError, "failed to check archive", err)
+			return
+		}
+		if archived {
+			respondErr(c, http.StatusConflict, "an approved project with this id already exists", nil)
+		} else {
+			respondErr(c, http.StatusNotFound, "deleted project not found", nil)
+		}
+		return
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "restore failed", err)
+		return
+	}
+
+	// 2. Its team
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO maintainers (p_id, user_id, m_name)
+         SELECT $1, m.user_id, m.m_name
+         FROM deleted_projects d, jsonb_to_recordset(d.maintainers) AS m(user_id INT, m_name TEXT)
+         WHERE d.p_id=$1`, pid); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to restore maintainers", err)
+		return
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO contributors (p_id, user_id, c_name)
+         SELECT $1, ct.user_id, ct.c_name
+         FROM deleted_projects d, jsonb_to_recordset(d.contributors) AS ct(user_id INT, c_name TEXT)
+         WHERE d.p_id=$1`, pid); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to restore contributors", err)
+		return
+	}
+
+	// 3. Leave the archive and note it in the status history
+	if _, err := tx.Exec(ctx, `DELETE FROM deleted_projects WHERE p_id=$1`, pid); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to clear archive", err)
+		return
+	}
+	if err := recordStatusChange(ctx, tx, pid, "", status, adminID, "restored from archive"); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to record status", err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		respondErr(c, http.StatusInternalServerError, "commit failed", err)
+		return
+	}
+
+	p, err := loadProject(ctx, conn, pid)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to load restored project", err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
@@ -4,38 +4,60 @@ package main
 import (
 	"context"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
 )
 
-// GET /admin/pending - list all projects awaiting approval
-func getPendingProjects(c *gin.Context) {
-	rows, err := conn.Query(context.Background(),
-		"SELECT r_id, name, description, creator_id, creator_name, status, submitted_at FROM buffer_projects WHERE status='pending'")
-	if err != nil {
-		respondErr(c, http.StatusInternalServerError, "failed to fetch pending projects", err)
-		return
-	}
-	defer rows.Close()
+// pendingListSpec backs GET /admin/pending; oldest submissions first.
+var pendingListSpec = listSpec[BufferProject]{
+	from:     "buffer_projects",
+	columns:  "r_id, name, description, creator_id, creator_name, status, submitted_at, category, tags, tech_stack, planned_start, planned_end, rejected_at, legal_hold",
+	idColumn: "r_id",
+	id:       func(p BufferProject) int { return p.RID },
+	sorts: map[string]sortField[BufferProject]{
+		"r_id":         {"r_id", "int", func(p BufferProject) string { return strconv.Itoa(p.RID) }},
+		"name":         {"name", "text", func(p BufferProject) string { return p.Name }},
+		"submitted_at": {"submitted_at", "timestamptz", func(p BufferProject) string { return p.SubmittedAt.Format(timeKeyLayout) }},
+	},
+	defaultSort: "submitted_at",
+}
 
-	// This struct is in models.go
-	var out []BufferProject
-	// pgx.AssignRows is a helper to scan all rows into a slice
-	if err = pgx.AssignRows(rows, &out); err != nil {
-		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+// GET /admin/pending - list projects awaiting approval
+// Filters: creator_id, name_prefix, category, tag, tech, submitted_from, submitted_to (YYYY-MM-DD).
+// Paging: sort (r_id|name|submitted_at), order, limit, cursor.
+func getPendingProjects(c *gin.Context) {
+	var f listFilter
+	f.add("status = 'pending'")
+	if !addCommonFilters(c, &f) || !addTaxonomyFilters(c, &f) ||
+		!addDateRange(c, &f, "submitted_at", "submitted_from", "submitted_to") {
 		return
 	}
-	c.JSON(http.StatusOK, out)
+	listPage(c, pendingListSpec, &f)
 }
 
 // POST /admin/approve/:id - approve a pending project
+// An optional {start_date, end_date} body overrides the creator's planned
+// dates; without any start date the project starts today.
 func approveProject(c *gin.Context) {
 	rid, err := getIntParam(c, "id")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid buffer project id"})
 		return
 	}
+	var dates plannedDates
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&dates); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+	start, end, ok := dates.parse(c, nil)
+	if !ok {
+		return
+	}
 
 	tx, err := conn.Begin(context.Background())
 	if err != nil {
@@ -45,14 +67,36 @@ func approveProject(c *gin.Context) {
 	defer tx.Rollback(context.Background())
 
 	// 1. Move from buffer_projects to approved_projects
-	// UPDATED: Status is now set to 'upcoming' instead of 'in_progress'
-	if _, err := tx.Exec(context.Background(),
-		`INSERT INTO approved_projects (name, description, creator_id, creator_name, start_date, status)
-         SELECT name, description, creator_id, creator_name, CURRENT_DATE, 'upcoming' 
-         FROM buffer_projects WHERE r_id=$1 AND status='pending'`, rid); err != nil {
+	// New projects start the lifecycle as 'upcoming' (see projectTransitions).
+	// Category, tags and tech stack carry over.
+	var pid int
+	var startDate time.Time
+	var endDate *time.Time
+	err = tx.QueryRow(context.Background(),
+		`INSERT INTO approved_projects (name, description, creator_id, creator_name, start_date, end_date, status, category, tags, tech_stack)
+         SELECT name, description, creator_id, creator_name, COALESCE($3, planned_start, `+sqlToday+`), COALESCE($4, planned_end),
+                $2, category, tags, tech_stack 
+         FROM buffer_projects WHERE r_id=$1 AND status='pending' RETURNING p_id, start_date, end_date`,
+		rid, StatusUpcoming, start, end).Scan(&pid, &startDate, &endDate)
+	if err == pgx.ErrNoRows {
+		respondErr(c, http.StatusNotFound, "project not found or not pending", nil)
+		return
+	}
+	if err != nil {
 		respondErr(c, http.StatusInternalServerError, "approval insert failed", err)
 		return
 	}
+	// Only one of the dates may have been adjusted
+	if endDate != nil && endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date cannot be before start_date",
+			"start_date": startDate.Format(dateKeyLayout), "end_date": endDate.Format(dateKeyLayout)})
+		return
+	}
+	adminID, _ := getActorID(c)
+	if err := recordStatusChange(context.Background(), tx, pid, "", StatusUpcoming, adminID, "approved"); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to record status", err)
+		return
+	}
 
 	// 2. Delete from buffer_projects
 	cmdTag, err := tx.Exec(context.Background(), `DELETE FROM buffer_projects WHERE r_id=$1`, rid)
@@ -72,7 +116,8 @@ func approveProject(c *gin.Context) {
 	}
 
 	// Respond with the new status
-	c.JSON(http.StatusOK, gin.H{"status": "approved", "new_project_status": "upcoming"})
+	c.JSON(http.StatusOK, gin.H{"status": "approved", "p_id": pid, "new_project_status": StatusUpcoming,
+		"start_date": startDate.Format(dateKeyLayout), "end_date": endDate})
 }
 
 // POST /admin/reject/:id - reject a pending project
@@ -85,7 +130,7 @@ func rejectProject(c *gin.Context) {
 
 	// This just updates the status in the buffer table, as requested.
 	cmdTag, err := conn.Exec(context.Background(),
-		`UPDATE buffer_projects SET status='rejected' WHERE r_id=$1 AND status='pending'`, rid)
+		`UPDATE buffer_projects SET status='rejected', rejected_at=now() WHERE r_id=$1 AND status='pending'`, rid)
 	if err != nil {
 		respondErr(c, http.StatusInternalServerError, "reject failed", err)
 		return
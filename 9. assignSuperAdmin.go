@@ -2,7 +2,9 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,17 +24,50 @@ func assignSuperAdmin(c *gin.Context) {
 		return
 	}
 
+	// An expiry, if given, must be in the future
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	actorID, ok := getActorID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return
+	}
+
+	// Grants that need a second superadmin become a pending request
+	pending, err := grantNeedsConfirmation(context.Background(), SpaceSuperadmins, actorID)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to check grant policy", err)
+		return
+	}
+	if pending {
+		id, err := createRoleChangeRequest(context.Background(), roleChange{
+			Action: roleActionGrant, UserID: req.UserID, UserName: req.UserName,
+			Space: SpaceSuperadmins, GrantExpiresAt: req.ExpiresAt,
+		}, actorID)
+		if err != nil {
+			respondErr(c, http.StatusInternalServerError, "failed to file grant request", err)
+			return
+		}
+		respondRoleChangePending(c, id, "Superadmin grant must be confirmed by another superadmin")
+		return
+	}
+
 	// Use the helper from auth.go to update the 'names' table
 	// and call auth.Create_permissions.
-	err := AssignMemberToSpace(req.UserID, req.UserName, SpaceSuperadmins)
+	err = AssignMemberToSpaceUntil(req.UserID, req.UserName, SpaceSuperadmins, req.ExpiresAt)
 	if err != nil {
 		respondErr(c, http.StatusInternalServerError, "failed to assign superadmin role", err)
 		return
 	}
+	recordAudit(context.Background(), actorID, req.UserID, "role_granted", SpaceSuperadmins)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Superadmin role assigned successfully",
-		"user_id": req.UserID,
-		"space":   SpaceSuperadmins,
+		"message":    "Superadmin role assigned successfully",
+		"user_id":    req.UserID,
+		"space":      SpaceSuperadmins,
+		"expires_at": req.ExpiresAt,
 	})
 }
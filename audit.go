@@ -0,0 +1,72 @@
+// audit.go
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------------------- Audit trail ----------------------
+
+// AuditEntry represents a record in the 'audit_log' table.
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	At        time.Time `json:"at"`
+	ActorID   *int      `json:"actor_id"`   // nil for background jobs
+	SubjectID *int      `json:"subject_id"` // user acted upon / acted as
+	Action    string    `json:"action"`
+	Detail    string    `json:"detail"`
+}
+
+// recordAudit appends to the audit log. An ID of 0 is stored as NULL.
+// Failures are logged, never returned: auditing must not break the action.
+func recordAudit(ctx context.Context, actorID, subjectID int, action, detail string) {
+	if _, err := conn.Exec(ctx,
+		`INSERT INTO audit_log (actor_id, subject_id, action, detail) VALUES ($1, $2, $3, $4)`,
+		nullableID(actorID), nullableID(subjectID), action, detail); err != nil {
+		log.Printf("Error: audit %s by %d on %d: %v\n", action, actorID, subjectID, err)
+	}
+}
+
+// nullableID maps the zero ID to SQL NULL.
+func nullableID(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// GET /superadmin/audit-log?actor_id=&subject_id=&action=&limit=
+// Most recent entries first.
+func getAuditLog(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+
+	rows, err := conn.Query(context.Background(),
+		`SELECT id, at, actor_id, subject_id, action, detail FROM audit_log
+         WHERE ($1 = '' OR actor_id = NULLIF($1, '')::int)
+           AND ($2 = '' OR subject_id = NULLIF($2, '')::int)
+           AND ($3 = '' OR action = $3)
+         ORDER BY id DESC LIMIT $4`,
+		c.Query("actor_id"), c.Query("subject_id"), c.Query("action"), limit)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch audit log", err)
+		return
+	}
+	out, err := pgx.CollectRows(rows, pgx.RowToStructByPos[AuditEntry])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
@@ -3,42 +3,50 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/GCET-Open-Source-Foundation/auth"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
 )
 
 // ---------------------- Auth helpers (use ONLY real auth functions) ----------------------
 
-// HasRole implements the hierarchy based on your new rules.
-// - "superadmin": checks SpaceSuperadmins
-// - "admin": checks ONLY SpaceAdmins
-// - "user": allows any authenticated user
+// HasRole reports whether the user holds the required role, directly or
+// through a role that includes it (see roleGraph in roles.go).
+// Unknown roles are never held.
 func HasRole(userIDStr string, require string) bool {
-	if require == "superadmin" {
-		return auth.Check_permissions(userIDStr, SpaceSuperadmins, MemberRole)
-	}
-	if require == "admin" {
-		// UPDATED: Only checks SpaceAdmins, does not include superadmin.
-		return auth.Check_permissions(userIDStr, SpaceAdmins, MemberRole)
-	}
-	if require == "user" {
-		// "user" or any other - treat as allowed for any authenticated user
-		return true
+	return hasRoleFor(nil, userIDStr, require)
+}
+
+// hasRoleFor is HasRole with lookups memoized on the request (see checkPermission).
+func hasRoleFor(c *gin.Context, userIDStr string, require string) bool {
+	for _, role := range grantedBy[require] {
+		if holdsDirectly(c, userIDStr, role) {
+			return true
+		}
 	}
-	// Default to false if role not recognized
 	return false
 }
 
-// AssignMemberToSpace assigns (creates) a membership using auth.Create_permissions.
+// AssignMemberToSpace assigns (creates) a permanent membership using auth.Create_permissions.
 func AssignMemberToSpace(userID int, userName, space string) error {
+	return AssignMemberToSpaceUntil(userID, userName, space, nil)
+}
+
+// AssignMemberToSpaceUntil is AssignMemberToSpace with an optional expiry,
+// after which the role sweeper revokes the membership. Granting again
+// replaces the previous expiry (nil makes the grant permanent).
+func AssignMemberToSpaceUntil(userID int, userName, space string, expiresAt *time.Time) error {
 	// Upsert into local names table (domain user store)
-	if _, err := conn.Exec(context.Background(),
-		`INSERT INTO names (id, name) VALUES ($1,$2) ON CONFLICT (id) DO UPDATE SET name=EXCLUDED.name`,
-		userID, userName); err != nil {
+	if err := upsertName(context.Background(), conn, userID, userName); err != nil {
 		return fmt.Errorf("upsert names failed: %w", err)
 	}
 
@@ -46,35 +54,322 @@ func AssignMemberToSpace(userID int, userName, space string) error {
 	if err := auth.Create_permissions(uidToStr(userID), space, MemberRole); err != nil {
 		return fmt.Errorf("auth.Create_permissions failed: %w", err)
 	}
+	permissionCache.invalidate(uidToStr(userID), space)
+
+	if _, err := conn.Exec(context.Background(),
+		`INSERT INTO role_grants (user_id, space, expires_at) VALUES ($1, $2, $3)
+         ON CONFLICT (space, user_id) DO UPDATE SET expires_at = EXCLUDED.expires_at`,
+		userID, space, expiresAt); err != nil {
+		return fmt.Errorf("record role grant failed: %w", err)
+	}
 	return nil
 }
 
+// errLastSuperadmin is returned when a revocation would leave no superadmin.
+var errLastSuperadmin = errors.New("cannot remove the last superadmin")
+
 // RemoveMemberFromSpace revokes membership using auth.Delete_permission.
+// Losing a role also ends all of the user's sessions, so the old access
+// tokens cannot keep using it.
 func RemoveMemberFromSpace(userID int, space string) error {
-	if err := auth.Delete_permission(uidToStr(userID), space, MemberRole); err != nil {
-		return fmt.Errorf("auth.Delete_permission failed: %w", err)
+	return RemoveMembersFromSpace([]int{userID}, space)
+}
+
+// RemoveMembersFromSpace revokes several memberships at once. For the
+// superadmins space the whole batch is refused with errLastSuperadmin if it
+// would leave no superadmin; bulk tooling must go through here so it cannot
+// bypass that check.
+//
+// The local grants are removed and committed first, then the auth library
+// memberships. If one of those fails, the grants of the users still holding
+// the membership are put back, so role_grants never lists fewer members than
+// the auth store (which would let the guard pass wrongly).
+func RemoveMembersFromSpace(userIDs []int, space string) error {
+	ctx := context.Background()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if space == SpaceSuperadmins {
+		if err := guardLastSuperadmin(ctx, tx, userIDs); err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.Query(ctx,
+		`DELETE FROM role_grants WHERE space = $1 AND user_id = ANY($2)
+         RETURNING user_id, granted_at, expires_at`, space, userIDs)
+	if err != nil {
+		return fmt.Errorf("delete role grants failed: %w", err)
+	}
+	deleted, err := pgx.CollectRows(rows, pgx.RowToStructByPos[removedGrant])
+	if err != nil {
+		return fmt.Errorf("delete role grants failed: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	// Stop at the first failure; the rest keep their membership.
+	removed := make([]int, 0, len(userIDs))
+	var delErr error
+	for i, id := range userIDs {
+		if err := auth.Delete_permission(uidToStr(id), space, MemberRole); err != nil {
+			delErr = fmt.Errorf("auth.Delete_permission failed: %w", err)
+			if restoreErr := restoreRoleGrants(ctx, space, deleted, userIDs[i:]); restoreErr != nil {
+				log.Printf("Error: %s grants of users %v are missing from role_grants: %v\n", space, userIDs[i:], restoreErr)
+				delErr = fmt.Errorf("%w (and restoring role grants failed: %v)", delErr, restoreErr)
+			}
+			break
+		}
+		permissionCache.invalidate(uidToStr(id), space)
+		removed = append(removed, id)
+	}
+
+	if isRoleSpace(space) {
+		for _, id := range removed {
+			if _, err := revokeUserSessions(ctx, id); err != nil {
+				return fmt.Errorf("revoke sessions failed: %w", err)
+			}
+		}
+	}
+	return delErr
+}
+
+// removedGrant is a role_grants row deleted by RemoveMembersFromSpace.
+type removedGrant struct {
+	UserID    int
+	GrantedAt time.Time
+	ExpiresAt *time.Time
+}
+
+// restoreRoleGrants puts back the deleted grants of userIDs.
+func restoreRoleGrants(ctx context.Context, space string, deleted []removedGrant, userIDs []int) error {
+	keep := make(map[int]bool, len(userIDs))
+	for _, id := range userIDs {
+		keep[id] = true
+	}
+	for _, g := range deleted {
+		if !keep[g.UserID] {
+			continue
+		}
+		if _, err := conn.Exec(ctx,
+			`INSERT INTO role_grants (user_id, space, granted_at, expires_at) VALUES ($1, $2, $3, $4)
+             ON CONFLICT (space, user_id) DO NOTHING`,
+			g.UserID, space, g.GrantedAt, g.ExpiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// guardLastSuperadmin locks the superadmin grants and fails if removing
+// userIDs would leave none. Memberships made before role_grants existed are
+// counted once backfillRoleGrants has recorded them.
+func guardLastSuperadmin(ctx context.Context, tx pgx.Tx, userIDs []int) error {
+	var remaining int
+	if err := tx.QueryRow(ctx,
+		`SELECT count(*) FROM (
+             SELECT user_id FROM role_grants WHERE space = $1 FOR UPDATE
+         ) g WHERE NOT (user_id = ANY($2))`,
+		SpaceSuperadmins, userIDs).Scan(&remaining); err != nil {
+		return fmt.Errorf("count superadmins failed: %w", err)
+	}
+	if remaining == 0 {
+		return errLastSuperadmin
+	}
+	return nil
+}
+
+// backfillRoleGrants records in role_grants the memberships of every role
+// space that were made before the table existed (or outside this service).
+// The auth library cannot list a space, so each user in 'names' is checked.
+// Each space is backfilled once per database (see runOnce in schema.go).
+func backfillRoleGrants(ctx context.Context) error {
+	for _, def := range roleGraph {
+		if def.Space == "" {
+			continue
+		}
+		space := def.Space
+		if err := runOnce(ctx, "role_grants_backfill:"+space, func(ctx context.Context) error {
+			rows, err := conn.Query(ctx, `SELECT id FROM names ORDER BY id`)
+			if err != nil {
+				return err
+			}
+			ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+			if err != nil {
+				return err
+			}
+			found := 0
+			for _, id := range ids {
+				if !auth.Check_permissions(uidToStr(id), space, MemberRole) {
+					continue
+				}
+				if _, err := conn.Exec(ctx,
+					`INSERT INTO role_grants (user_id, space) VALUES ($1, $2) ON CONFLICT (space, user_id) DO NOTHING`,
+					id, space); err != nil {
+					return err
+				}
+				found++
+			}
+			log.Printf("Backfilled %d %s grant(s) into role_grants\n", found, space)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("backfill %s grants: %w", space, err)
+		}
 	}
 	return nil
 }
 
 // ---------------------- Middleware ----------------------
 
-// DummyAuthMiddleware placeholder
+// AuthMiddleware returns the authentication middleware for protected routes.
+// Signed bearer tokens are required unless dummy auth was explicitly enabled
+// with PF_DEV_DUMMY_AUTH=1 (local development only).
+func AuthMiddleware() gin.HandlerFunc {
+	if devDummyAuth {
+		log.Println("WARNING: PF_DEV_DUMMY_AUTH is set, trusting X-Dummy-User headers")
+		return DummyAuthMiddleware()
+	}
+	return BearerAuthMiddleware(jwtKeys)
+}
+
+// OptionalAuthMiddleware authenticates requests that carry credentials, like
+// AuthMiddleware, and lets anonymous ones through. For public routes that
+// show more to some users.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	authenticate := AuthMiddleware()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" && c.GetHeader("X-API-Key") == "" && c.GetHeader("X-Dummy-User") == "" {
+			c.Next()
+			return
+		}
+		authenticate(c)
+	}
+}
+
+// BearerAuthMiddleware verifies "Authorization: Bearer <jwt>" (HS256 or RS256)
+// and stores the token's subject as the authenticated user. Personal API keys
+// are accepted as a bearer token or in the X-API-Key header.
+func BearerAuthMiddleware(keys *jwtKeySet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			authenticateAPIKey(c, key)
+			return
+		}
+
+		scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		token = strings.TrimSpace(token)
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			c.Abort()
+			return
+		}
+		if strings.HasPrefix(token, apiKeyPrefix) {
+			authenticateAPIKey(c, token)
+			return
+		}
+
+		claims, uid, err := keys.verifyAccessToken(token)
+		if err != nil {
+			msg := "invalid token"
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				msg = "token expired"
+			}
+			log.Printf("Auth: rejected bearer token: %v\n", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+			c.Abort()
+			return
+		}
+
+		// Tokens issued by us are bound to a server-side session that can be revoked.
+		if claims.SessionID != 0 {
+			active, err := sessionActive(context.Background(), claims.SessionID, uid)
+			if err != nil {
+				respondErr(c, http.StatusInternalServerError, "failed to check session", err)
+				c.Abort()
+				return
+			}
+			if !active {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+				c.Abort()
+				return
+			}
+			c.Set("session_id", claims.SessionID)
+		}
+
+		// Impersonation tokens are validated by ImpersonationMiddleware.
+		if claims.Actor != nil {
+			actorID, err := strconv.Atoi(claims.Actor.Subject)
+			if err != nil || actorID <= 0 {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+				c.Abort()
+				return
+			}
+			c.Set("actor_id_int", actorID)
+		}
+
+		setAuthUser(c, uid)
+		c.Next()
+	}
+}
+
+// authenticateAPIKey authenticates the request as the owner of a personal API key
+// and records the key's scopes for RequireRole.
+func authenticateAPIKey(c *gin.Context, raw string) {
+	key, err := lookupAPIKey(context.Background(), raw)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to check API key", err)
+		c.Abort()
+		return
+	}
+	if key == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid, revoked or expired API key"})
+		c.Abort()
+		return
+	}
+
+	c.Set("api_key_id", key.ID)
+	c.Set("api_key_scopes", key.Scopes)
+	setAuthUser(c, key.UserID)
+	c.Next()
+}
+
+// DummyAuthMiddleware trusts the X-Dummy-User header. Development only,
+// see AuthMiddleware.
 func DummyAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		uid := c.GetHeader("X-Dummy-User")
-		c.Set("user_id", uid)
-		
-		// Also fetch and set the user's ID as an integer for handlers
-		uidInt, _ := strconv.Atoi(uid)
-		c.Set("user_id_int", uidInt)
+		uid, err := strconv.Atoi(c.GetHeader("X-Dummy-User"))
+		if err != nil || uid <= 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid X-Dummy-User header"})
+			c.Abort()
+			return
+		}
 
+		setAuthUser(c, uid)
 		c.Next()
 	}
 }
 
-// RequireRole middleware uses the updated HasRole()
-func RequireRole(required string) gin.HandlerFunc {
+// setAuthUser fills the context keys read by RequireRole ("user_id") and
+// getUserID ("user_id_int").
+func setAuthUser(c *gin.Context, uid int) {
+	c.Set("user_id", uidToStr(uid))
+	c.Set("user_id_int", uid)
+}
+
+// RequireRole middleware uses the updated HasRole(), memoized per request. Requests authenticated
+// with an API key must also hold the given scopes, or the role's default
+// scope (roleDefaultScopes) when none are given.
+func RequireRole(required string, scopes ...string) gin.HandlerFunc {
+	if len(scopes) == 0 {
+		if s, ok := roleDefaultScopes[required]; ok {
+			scopes = []string{s}
+		}
+	}
+
 	return func(c *gin.Context) {
 		val, exists := c.Get("user_id")
 		if !exists {
@@ -88,11 +383,16 @@ func RequireRole(required string) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		if !HasRole(userIDStr, required) {
+		if !hasRoleFor(c, userIDStr, required) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied for this role"})
 			c.Abort()
 			return
 		}
+		if missing := missingScopes(c, scopes); len(missing) > 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing required scopes", "missing_scopes": missing})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
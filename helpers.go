@@ -2,11 +2,16 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // respondErr logs the error and sends a JSON error message.
@@ -39,3 +44,88 @@ func getUserID(c *gin.Context) (int, bool) {
 	uid, ok := val.(int)
 	return uid, ok
 }
+
+// getActorID returns who is really making the request: the superadmin behind
+// an impersonated request, otherwise the authenticated user.
+func getActorID(c *gin.Context) (int, bool) {
+	if val, exists := c.Get("actor_id_int"); exists {
+		uid, ok := val.(int)
+		return uid, ok
+	}
+	return getUserID(c)
+}
+
+// execer is satisfied by both the connection pool and a pgx.Tx.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// upsertName creates or renames the user's row in the 'names' table.
+func upsertName(ctx context.Context, db execer, userID int, userName string) error {
+	_, err := db.Exec(ctx,
+		`INSERT INTO names (id, name) VALUES ($1,$2) ON CONFLICT (id) DO UPDATE SET name=EXCLUDED.name`,
+		userID, userName)
+	return err
+}
+
+// userIDInUse reports whether userID already has a 'names' row, a role
+// grant or a linked SSO identity, and returns the name if there is one.
+// New accounts must not be handed such an ID. It also locks userID until
+// tx ends, so two sign-ups for the same ID cannot both pass the check.
+func userIDInUse(ctx context.Context, tx pgx.Tx, userID int) (name *string, inUse bool, err error) {
+	if _, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('names.id'), $1)`, userID); err != nil {
+		return nil, false, err
+	}
+	err = tx.QueryRow(ctx,
+		`SELECT (SELECT name FROM names WHERE id=$1),
+                EXISTS(SELECT 1 FROM role_grants WHERE user_id=$1)
+                OR EXISTS(SELECT 1 FROM oidc_identities WHERE user_id=$1)`,
+		userID).Scan(&name, &inUse)
+	return name, inUse || name != nil, err
+}
+
+// pageParams reads ?limit= (see limitParam) and ?offset= for list
+// endpoints, writing the 400 itself when they are invalid.
+func pageParams(c *gin.Context) (limit, offset int, ok bool) {
+	if limit, ok = limitParam(c); !ok {
+		return 0, 0, false
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+		return 0, 0, false
+	}
+	return limit, offset, true
+}
+
+// limitParam reads ?limit= (default 50, at most 200), writing the 400 itself.
+func limitParam(c *gin.Context) (int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return 0, false
+	}
+	return limit, true
+}
+
+// envDuration reads a duration (e.g. "15m") from the environment, or returns def.
+func envDuration(name string, def time.Duration) time.Duration {
+	if v := os.Getenv(name); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+		log.Printf("Warning: invalid duration %s=%q, using %s\n", name, v, def)
+	}
+	return def
+}
+
+// envInt reads an integer from the environment, or returns def.
+func envInt(name string, def int) int {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+		log.Printf("Warning: invalid integer %s=%q, using %d\n", name, v, def)
+	}
+	return def
+}
@@ -0,0 +1,98 @@
+// jobs.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ---------------------- Background jobs ----------------------
+
+// startJob runs fn now and then every interval in the background. With
+// several instances, a Postgres advisory lock keyed on name makes sure only
+// one of them runs the job at a time. A non-positive interval disables it.
+func startJob(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	if interval <= 0 {
+		log.Printf("Job %s disabled\n", name)
+		return
+	}
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			runJobOnce(name, fn)
+			<-t.C
+		}
+	}()
+}
+
+// runJobOnce runs fn if no other instance is running the same job.
+func runJobOnce(name string, fn func(ctx context.Context) error) {
+	ctx := context.Background()
+	c, err := conn.Acquire(ctx)
+	if err != nil {
+		log.Printf("Error: job %s: acquire connection: %v\n", name, err)
+		return
+	}
+	defer c.Release()
+
+	// Advisory locks belong to the session, so lock and unlock on the same connection.
+	lockKey := "pf_job:" + name
+	var locked bool
+	if err := c.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, lockKey).Scan(&locked); err != nil {
+		log.Printf("Error: job %s: lock: %v\n", name, err)
+		return
+	}
+	if !locked {
+		return
+	}
+	defer func() {
+		if _, err := c.Exec(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, lockKey); err != nil {
+			log.Printf("Error: job %s: unlock: %v\n", name, err)
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		log.Printf("Error: job %s: %v\n", name, err)
+	}
+}
+
+// ---------------------- Housekeeping ----------------------
+
+// oidcStateSweepInterval is how often abandoned SSO logins are deleted
+// (PF_OIDC_STATE_SWEEP, default 1h; 0 disables it).
+var oidcStateSweepInterval = envDuration("PF_OIDC_STATE_SWEEP", time.Hour)
+
+// sweepExpiredOIDCStates deletes logins that were started but never finished
+// within oidcStateTTL.
+func sweepExpiredOIDCStates(ctx context.Context) error {
+	tag, err := conn.Exec(ctx,
+		`DELETE FROM oidc_login_states WHERE created_at <= $1`, time.Now().Add(-oidcStateTTL))
+	if err != nil {
+		return fmt.Errorf("delete expired login states: %w", err)
+	}
+	if n := tag.RowsAffected(); n > 0 {
+		log.Printf("Deleted %d expired login state(s)\n", n)
+	}
+	return nil
+}
+
+// rateBucketSweepInterval is how often idle shared rate limit buckets are
+// deleted (PF_RATE_BUCKET_SWEEP, default 15m; 0 disables it).
+var rateBucketSweepInterval = envDuration("PF_RATE_BUCKET_SWEEP", 15*time.Minute)
+
+// sweepIdleRateBuckets deletes rate_limit_buckets rows unused for
+// pgRateBucketIdle, so one row per client IP does not pile up forever.
+func sweepIdleRateBuckets(ctx context.Context) error {
+	tag, err := conn.Exec(ctx,
+		`DELETE FROM rate_limit_buckets WHERE updated_at <= $1`, time.Now().Add(-pgRateBucketIdle))
+	if err != nil {
+		return fmt.Errorf("delete idle rate limit buckets: %w", err)
+	}
+	if n := tag.RowsAffected(); n > 0 {
+		log.Printf("Deleted %d idle rate limit bucket(s)\n", n)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+// jwt.go
+package main
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// accessTokenTTL is how long issued access tokens stay valid (PF_JWT_ACCESS_TTL).
+var accessTokenTTL = envDuration("PF_JWT_ACCESS_TTL", 15*time.Minute)
+
+// ---------------------- Token verification ----------------------
+
+// accessClaims are the claims carried by a bearer access token.
+// The subject ("sub") is the user's names.id as a decimal string.
+// Tokens we issue also carry the auth_sessions.id they belong to ("sid"),
+// and impersonation tokens name the real superadmin in "act" (RFC 8693).
+type accessClaims struct {
+	jwt.RegisteredClaims
+	SessionID int64        `json:"sid,omitempty"`
+	Actor     *actorClaims `json:"act,omitempty"`
+}
+
+// actorClaims identifies who is really acting behind an impersonation token.
+type actorClaims struct {
+	Subject string `json:"sub"`
+}
+
+// jwtKeySet holds every key a bearer token may be signed with, indexed by "kid".
+type jwtKeySet struct {
+	hmac     map[string][]byte         // HS256 secrets
+	rsa      map[string]*rsa.PublicKey // RS256 public keys
+	issuer   string                    // optional, enforced when set
+	audience string                    // optional, enforced when set
+
+	// Signing key for tokens we issue ourselves; nil if this instance only verifies.
+	signKid    string
+	signMethod jwt.SigningMethod
+	signKey    interface{}
+}
+
+// loadJWTKeySetFromEnv builds the key set from the environment:
+//   - PF_JWT_HS256_KEYS: "kid=secret,kid2=secret2" (a bare secret gets kid "default")
+//   - PF_JWT_RS256_KEYS: "kid=/path/to/public.pem,..."
+//   - PF_JWT_ISSUER / PF_JWT_AUDIENCE: optional "iss" / "aud" to require
+//   - PF_JWT_SIGNING_KID: kid used to sign issued tokens
+//   - PF_JWT_RS256_SIGNING_KEY: path to the RS256 private key for PF_JWT_SIGNING_KID
+func loadJWTKeySetFromEnv() (*jwtKeySet, error) {
+	ks := &jwtKeySet{
+		hmac:     map[string][]byte{},
+		rsa:      map[string]*rsa.PublicKey{},
+		issuer:   os.Getenv("PF_JWT_ISSUER"),
+		audience: os.Getenv("PF_JWT_AUDIENCE"),
+	}
+
+	for kid, secret := range parseKeyList(os.Getenv("PF_JWT_HS256_KEYS")) {
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("HS256 key %q must be at least 32 bytes", kid)
+		}
+		ks.hmac[kid] = []byte(secret)
+	}
+
+	for kid, path := range parseKeyList(os.Getenv("PF_JWT_RS256_KEYS")) {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read RS256 key %q: %w", kid, err)
+		}
+		pub, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("parse RS256 key %q: %w", kid, err)
+		}
+		ks.rsa[kid] = pub
+	}
+
+	if err := ks.loadSigningKey(os.Getenv("PF_JWT_SIGNING_KID"), os.Getenv("PF_JWT_RS256_SIGNING_KEY")); err != nil {
+		return nil, err
+	}
+
+	if len(ks.hmac) == 0 && len(ks.rsa) == 0 {
+		return nil, errors.New("no JWT keys configured (set PF_JWT_HS256_KEYS or PF_JWT_RS256_KEYS)")
+	}
+	return ks, nil
+}
+
+// loadSigningKey selects the key used by signAccessToken. An RS256 private key
+// takes precedence; otherwise the HS256 secret for kid (or the only one) is used.
+func (ks *jwtKeySet) loadSigningKey(kid, rsaPrivPath string) error {
+	if rsaPrivPath != "" {
+		if kid == "" {
+			return errors.New("PF_JWT_SIGNING_KID is required with PF_JWT_RS256_SIGNING_KEY")
+		}
+		pem, err := os.ReadFile(rsaPrivPath)
+		if err != nil {
+			return fmt.Errorf("read RS256 signing key: %w", err)
+		}
+		priv, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+		if err != nil {
+			return fmt.Errorf("parse RS256 signing key: %w", err)
+		}
+		ks.rsa[kid] = &priv.PublicKey
+		ks.signKid, ks.signMethod, ks.signKey = kid, jwt.SigningMethodRS256, priv
+		return nil
+	}
+
+	if secret, ok := pickKey(ks.hmac, kid); ok {
+		if kid == "" {
+			for k := range ks.hmac {
+				kid = k
+			}
+		}
+		ks.signKid, ks.signMethod, ks.signKey = kid, jwt.SigningMethodHS256, secret
+	}
+	return nil
+}
+
+// signAccessToken issues an access token for uid, bound to session sid,
+// that expires after accessTokenTTL.
+func (ks *jwtKeySet) signAccessToken(uid int, sid int64) (string, time.Time, error) {
+	return ks.sign(accessClaims{SessionID: sid}, uid, accessTokenTTL)
+}
+
+// signImpersonationToken issues a token for target that records actorID as the
+// real user. It has no session and therefore cannot be refreshed.
+func (ks *jwtKeySet) signImpersonationToken(target, actorID int, ttl time.Duration) (string, time.Time, error) {
+	return ks.sign(accessClaims{Actor: &actorClaims{Subject: uidToStr(actorID)}}, target, ttl)
+}
+
+// sign fills the registered claims for uid and signs with the configured key.
+func (ks *jwtKeySet) sign(claims accessClaims, uid int, ttl time.Duration) (string, time.Time, error) {
+	if ks == nil || ks.signKey == nil {
+		return "", time.Time{}, errors.New("token signing is not configured")
+	}
+
+	now := time.Now()
+	exp := now.Add(ttl)
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Subject:   uidToStr(uid),
+		Issuer:    ks.issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(exp),
+	}
+	if ks.audience != "" {
+		claims.Audience = jwt.ClaimStrings{ks.audience}
+	}
+
+	t := jwt.NewWithClaims(ks.signMethod, claims)
+	t.Header["kid"] = ks.signKid
+	signed, err := t.SignedString(ks.signKey)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("sign token: %w", err)
+	}
+	return signed, exp, nil
+}
+
+// parseKeyList splits "kid=value,kid2=value2" into a map.
+// An entry without "=" is stored under the kid "default".
+func parseKeyList(s string) map[string]string {
+	out := map[string]string{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, val, found := strings.Cut(entry, "=")
+		if !found {
+			kid, val = "default", entry
+		}
+		out[strings.TrimSpace(kid)] = strings.TrimSpace(val)
+	}
+	return out
+}
+
+// keyFunc picks the verification key based on the token's "alg" and "kid" headers.
+// If the token has no kid and exactly one key of that type exists, that key is used.
+func (ks *jwtKeySet) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	switch t.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		if key, ok := pickKey(ks.hmac, kid); ok {
+			return key, nil
+		}
+	case jwt.SigningMethodRS256.Alg():
+		if key, ok := pickKey(ks.rsa, kid); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("no %s key for kid %q", t.Method.Alg(), kid)
+}
+
+// pickKey looks up kid in keys, falling back to the only key when kid is empty.
+func pickKey[K any](keys map[string]K, kid string) (K, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k, true
+		}
+	}
+	k, ok := keys[kid]
+	return k, ok
+}
+
+// verifyAccessToken validates the signature, expiry and claims of a bearer token
+// and returns its claims together with the numeric user ID from "sub".
+func (ks *jwtKeySet) verifyAccessToken(raw string) (*accessClaims, int, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if ks.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(ks.issuer))
+	}
+	if ks.audience != "" {
+		opts = append(opts, jwt.WithAudience(ks.audience))
+	}
+
+	claims := &accessClaims{}
+	if _, err := jwt.ParseWithClaims(raw, claims, ks.keyFunc, opts...); err != nil {
+		return nil, 0, err
+	}
+
+	uid, err := strconv.Atoi(claims.Subject)
+	if err != nil || uid <= 0 {
+		return nil, 0, fmt.Errorf("invalid subject %q", claims.Subject)
+	}
+	return claims, uid, nil
+}
@@ -0,0 +1,231 @@
+// jwt_test.go
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testHS256Secret = strings.Repeat("s", 32)
+
+// testKeySet returns an HS256 key set signing with kid, like PF_JWT_HS256_KEYS.
+func testKeySet(t *testing.T, kid, secret, issuer, audience string) *jwtKeySet {
+	t.Helper()
+	ks := &jwtKeySet{
+		hmac:     map[string][]byte{kid: []byte(secret)},
+		rsa:      map[string]*rsa.PublicKey{},
+		issuer:   issuer,
+		audience: audience,
+	}
+	if err := ks.loadSigningKey(kid, ""); err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+// testRSAKeySet returns a key set signing with a fresh RS256 key read from disk.
+func testRSAKeySet(t *testing.T) *jwtKeySet {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "signing.pem")
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ks := &jwtKeySet{hmac: map[string][]byte{}, rsa: map[string]*rsa.PublicKey{}}
+	if err := ks.loadSigningKey("rs1", path); err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+func TestJWTSignAndVerify(t *testing.T) {
+	hs := testKeySet(t, "k1", testHS256Secret, "", "")
+	rs := testRSAKeySet(t)
+
+	tests := []struct {
+		name    string
+		signer  *jwtKeySet
+		uid     int
+		ttl     time.Duration
+		mutate  func(string) string // changes the signed token before verifying
+		verify  *jwtKeySet          // defaults to signer
+		wantErr bool
+	}{
+		{name: "HS256", signer: hs, uid: 42, ttl: time.Minute},
+		{name: "RS256", signer: rs, uid: 42, ttl: time.Minute},
+		{name: "expired", signer: hs, uid: 42, ttl: -time.Minute, wantErr: true},
+		{name: "expired within leeway", signer: hs, uid: 42, ttl: -10 * time.Second},
+		{name: "zero subject", signer: hs, uid: 0, ttl: time.Minute, wantErr: true},
+		{name: "other secret", signer: hs, uid: 42, ttl: time.Minute,
+			verify: testKeySet(t, "k1", strings.Repeat("x", 32), "", ""), wantErr: true},
+		{name: "unknown kid", signer: hs, uid: 42, ttl: time.Minute,
+			verify: &jwtKeySet{hmac: map[string][]byte{"k1": []byte(testHS256Secret), "k2": []byte(testHS256Secret)},
+				rsa: map[string]*rsa.PublicKey{}},
+			mutate: func(s string) string { return reSignWithKid(t, s, "k3") }, wantErr: true},
+		{name: "RS256 token against HS256 keys", signer: rs, uid: 42, ttl: time.Minute, verify: hs, wantErr: true},
+		{name: "issuer required", signer: hs, uid: 42, ttl: time.Minute,
+			verify: testKeySet(t, "k1", testHS256Secret, "pf", ""), wantErr: true},
+		{name: "audience required", signer: hs, uid: 42, ttl: time.Minute,
+			verify: testKeySet(t, "k1", testHS256Secret, "", "pf-api"), wantErr: true},
+		{name: "issuer and audience match", signer: testKeySet(t, "k1", testHS256Secret, "pf", "pf-api"), uid: 42,
+			ttl: time.Minute, verify: testKeySet(t, "k1", testHS256Secret, "pf", "pf-api")},
+		{name: "tampered claims", signer: hs, uid: 42, ttl: time.Minute,
+			mutate: func(s string) string {
+				parts := strings.Split(s, ".")
+				parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"1","exp":9999999999,"iat":1}`))
+				return strings.Join(parts, ".")
+			}, wantErr: true},
+		{name: "garbage", signer: hs, uid: 42, ttl: time.Minute,
+			mutate: func(string) string { return "not.a.jwt" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, exp, err := tt.signer.sign(accessClaims{SessionID: 7}, tt.uid, tt.ttl)
+			if err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+			if d := time.Until(exp) - tt.ttl; d > time.Second || d < -time.Second {
+				t.Fatalf("expires at %v, want about now + %v", exp, tt.ttl)
+			}
+			if tt.mutate != nil {
+				raw = tt.mutate(raw)
+			}
+			verify := tt.verify
+			if verify == nil {
+				verify = tt.signer
+			}
+
+			claims, uid, err := verify.verifyAccessToken(raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("verified uid %d, want error", uid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if uid != tt.uid || claims.SessionID != 7 || claims.Actor != nil {
+				t.Fatalf("got uid %d, sid %d, actor %v", uid, claims.SessionID, claims.Actor)
+			}
+		})
+	}
+}
+
+// reSignWithKid re-signs an HS256 token's claims with the same secret under kid.
+func reSignWithKid(t *testing.T, raw, kid string) string {
+	t.Helper()
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(raw, claims); err != nil {
+		t.Fatal(err)
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tok.Header["kid"] = kid
+	s, err := tok.SignedString([]byte(testHS256Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestJWTAccessTokenTTL(t *testing.T) {
+	ks := testKeySet(t, "k1", testHS256Secret, "", "")
+	raw, exp, err := ks.signAccessToken(42, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(exp) - accessTokenTTL; d > time.Second || d < -time.Second {
+		t.Fatalf("expires at %v, want about now + %v", exp, accessTokenTTL)
+	}
+	if claims, uid, err := ks.verifyAccessToken(raw); err != nil || uid != 42 || claims.SessionID != 7 {
+		t.Fatalf("verify: uid %d, claims %+v, err %v", uid, claims, err)
+	}
+}
+
+func TestJWTImpersonationToken(t *testing.T) {
+	ks := testKeySet(t, "k1", testHS256Secret, "", "")
+	raw, _, err := ks.signImpersonationToken(42, 1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, uid, err := ks.verifyAccessToken(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid != 42 || claims.SessionID != 0 || claims.Actor == nil || claims.Actor.Subject != "1" {
+		t.Fatalf("got uid %d, sid %d, actor %+v", uid, claims.SessionID, claims.Actor)
+	}
+}
+
+func TestJWTSigningNotConfigured(t *testing.T) {
+	verifyOnly := &jwtKeySet{hmac: map[string][]byte{}, rsa: map[string]*rsa.PublicKey{}}
+	for name, ks := range map[string]*jwtKeySet{"nil key set": nil, "verify only": verifyOnly} {
+		if _, _, err := ks.signAccessToken(1, 1); err == nil {
+			t.Errorf("%s: signed a token without a signing key", name)
+		}
+	}
+}
+
+func TestLoadJWTKeySetFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		hs256   string
+		kid     string
+		wantErr bool
+		wantKid string
+	}{
+		{name: "no keys", wantErr: true},
+		{name: "short secret", hs256: "k1=short", wantErr: true},
+		{name: "bare secret", hs256: testHS256Secret, wantKid: "default"},
+		{name: "only key signs", hs256: "k1=" + testHS256Secret, wantKid: "k1"},
+		{name: "chosen signing kid", hs256: "k1=" + testHS256Secret + ", k2=" + testHS256Secret, kid: "k2", wantKid: "k2"},
+		{name: "signing kid without key", hs256: "k1=" + testHS256Secret + ",k2=" + testHS256Secret, kid: "k3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PF_JWT_HS256_KEYS", tt.hs256)
+			t.Setenv("PF_JWT_RS256_KEYS", "")
+			t.Setenv("PF_JWT_SIGNING_KID", tt.kid)
+			t.Setenv("PF_JWT_RS256_SIGNING_KEY", "")
+			ks, err := loadJWTKeySetFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && ks.signKid != tt.wantKid {
+				t.Fatalf("signing kid = %q, want %q", ks.signKid, tt.wantKid)
+			}
+		})
+	}
+}
+
+func TestParseKeyList(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]string
+	}{
+		{"", map[string]string{}},
+		{"secret", map[string]string{"default": "secret"}},
+		{"a=1,b=2", map[string]string{"a": "1", "b": "2"}},
+		{" a = 1 , , b=x=y ", map[string]string{"a": "1", "b": "x=y"}},
+	}
+	for _, tt := range tests {
+		if got := parseKeyList(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseKeyList(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// listing.go
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------------------- Filtered, keyset-paginated lists ----------------------
+
+// sortField is one ?sort= option of a list endpoint.
+type sortField[T any] struct {
+	column string         // SQL expression to order by
+	cast   string         // SQL type of the cursor value, e.g. "date"
+	key    func(T) string // the row's value, as stored in the cursor
+}
+
+// listSpec describes a list endpoint: what it selects and how it can be sorted.
+// Rows are always ordered by the sort column, then idColumn, in one direction,
+// so (sort value, id) is a stable keyset cursor.
+type listSpec[T any] struct {
+	from        string // table (and joins)
+	columns     string // select list, in the field order of T
+	idColumn    string
+	id          func(T) int
+	sorts       map[string]sortField[T]
+	defaultSort string
+	defaultDesc bool
+}
+
+// listFilter collects WHERE conditions and their arguments. Conditions are
+// written with "$?" placeholders, which are numbered as they are added.
+type listFilter struct {
+	conds []string
+	args  []any
+}
+
+func (f *listFilter) add(cond string, args ...any) {
+	for _, a := range args {
+		f.args = append(f.args, a)
+		cond = strings.Replace(cond, "$?", "$"+strconv.Itoa(len(f.args)), 1)
+	}
+	f.conds = append(f.conds, cond)
+}
+
+func (f *listFilter) where() string {
+	if len(f.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(f.conds, " AND ")
+}
+
+// listCursor is the opaque ?cursor= value: the last row's sort key and ID.
+type listCursor struct {
+	Sort string `json:"s"`
+	Desc bool   `json:"d"`
+	Key  string `json:"k"`
+	ID   int    `json:"id"`
+}
+
+// listPage answers a list request with {items, next_cursor, total}, reading
+// ?sort=, ?order=asc|desc, ?limit= and ?cursor=. f holds the endpoint's
+// filters; total counts every row matching them. It writes the response,
+// including 400s for bad parameters.
+func listPage[T any](c *gin.Context, spec listSpec[T], f *listFilter) {
+	// 1. Sort order
+	sortName := c.DefaultQuery("sort", spec.defaultSort)
+	sf, ok := spec.sorts[sortName]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of: " + strings.Join(sortNames(spec.sorts), ", ")})
+		return
+	}
+	desc := spec.defaultDesc
+	switch c.Query("order") {
+	case "":
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return
+	}
+	limit, ok := limitParam(c)
+	if !ok {
+		return
+	}
+	ctx := context.Background()
+
+	// 2. Total before the cursor narrows it down
+	var total int
+	if err := conn.QueryRow(ctx, `SELECT count(*) FROM `+spec.from+f.where(), f.args...).Scan(&total); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to count results", err)
+		return
+	}
+
+	// 3. Resume after the cursor
+	if raw := c.Query("cursor"); raw != "" {
+		cur, err := decodeListCursor(raw)
+		if err != nil || cur.Sort != sortName || cur.Desc != desc {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor for this sort order"})
+			return
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		f.add(fmt.Sprintf("(%s, %s) %s ($?::text::%s, $?)", sf.column, spec.idColumn, op, sf.cast), cur.Key, cur.ID)
+	}
+
+	// 4. One extra row tells whether there is a next page
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	args := append(f.args, limit+1)
+	rows, err := conn.Query(ctx,
+		fmt.Sprintf(`SELECT %s FROM %s%s ORDER BY %s %s, %s %s LIMIT $%d`,
+			spec.columns, spec.from, f.where(), sf.column, dir, spec.idColumn, dir, len(args)),
+		args...)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch results", err)
+		return
+	}
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByPos[T])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	var next *string
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[limit-1]
+		s := encodeListCursor(listCursor{Sort: sortName, Desc: desc, Key: sf.key(last), ID: spec.id(last)})
+		next = &s
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items, "next_cursor": next, "total": total})
+}
+
+func encodeListCursor(cur listCursor) string {
+	b, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeListCursor(raw string) (listCursor, error) {
+	var cur listCursor
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cur, err
+	}
+	err = json.Unmarshal(b, &cur)
+	return cur, err
+}
+
+func sortNames[T any](sorts map[string]sortField[T]) []string {
+	out := make([]string, 0, len(sorts))
+	for name := range sorts {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// --- Filter parameters ---
+
+// Cursor key formats for the common column types.
+const (
+	dateKeyLayout = "2006-01-02"
+	timeKeyLayout = time.RFC3339Nano
+)
+
+// queryInt reads an optional integer parameter, writing the 400 itself.
+func queryInt(c *gin.Context, name string) (*int, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be an integer"})
+		return nil, false
+	}
+	return &n, true
+}
+
+// queryDate reads an optional YYYY-MM-DD parameter, writing the 400 itself.
+func queryDate(c *gin.Context, name string) (*time.Time, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, true
+	}
+	t, err := time.Parse(dateKeyLayout, v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a date (YYYY-MM-DD)"})
+		return nil, false
+	}
+	return &t, true
+}
+
+// addCommonFilters adds ?creator_id= and ?name_prefix= (case-insensitive)
+// for tables with creator_id and name columns.
+func addCommonFilters(c *gin.Context, f *listFilter) bool {
+	creatorID, ok := queryInt(c, "creator_id")
+	if !ok {
+		return false
+	}
+	if creatorID != nil {
+		f.add("creator_id = $?", *creatorID)
+	}
+	if p := c.Query("name_prefix"); p != "" {
+		f.add(`name ILIKE $? ESCAPE '\'`, likePrefix(p))
+	}
+	return true
+}
+
+// addDateRange adds column >= ?<from> and column < the day after ?<to>.
+func addDateRange(c *gin.Context, f *listFilter, column, from, to string) bool {
+	start, ok1 := queryDate(c, from)
+	if !ok1 {
+		return false
+	}
+	end, ok2 := queryDate(c, to)
+	if !ok2 {
+		return false
+	}
+	if start != nil {
+		f.add(column+" >= $?", *start)
+	}
+	if end != nil {
+		f.add(column+" < $?", end.AddDate(0, 0, 1))
+	}
+	return true
+}
+
+// likePrefix escapes LIKE wildcards in p and appends '%'.
+func likePrefix(p string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(p) + "%"
+}
@@ -0,0 +1,58 @@
+// listing_test.go
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListCursorRoundTrip(t *testing.T) {
+	tests := []listCursor{
+		{Sort: "created", Desc: true, Key: "2025-01-02T03:04:05.123456789Z", ID: 17},
+		{Sort: "end_date", Key: "infinity", ID: 1},
+		{Sort: "title", Key: `a "quoted", ünïcode/title+=`, ID: 99},
+		{},
+	}
+	for _, cur := range tests {
+		raw := encodeListCursor(cur)
+		got, err := decodeListCursor(raw)
+		if err != nil {
+			t.Errorf("decodeListCursor(%q): %v", raw, err)
+			continue
+		}
+		if got != cur {
+			t.Errorf("round trip of %+v gave %+v", cur, got)
+		}
+	}
+}
+
+func TestDecodeListCursorInvalid(t *testing.T) {
+	tests := map[string]string{
+		"not base64":       "%%%",
+		"padded base64":    "eyJzIjoieCJ9==",
+		"not json":         "bm90IGpzb24",    // "not json"
+		"wrong field type": "eyJpZCI6IngifQ", // {"id":"x"}
+	}
+	for name, raw := range tests {
+		if cur, err := decodeListCursor(raw); err == nil {
+			t.Errorf("%s: decoded %+v, want error", name, cur)
+		}
+	}
+}
+
+func TestListFilterPlaceholders(t *testing.T) {
+	var f listFilter
+	if got := f.where(); got != "" {
+		t.Fatalf("empty filter: where() = %q", got)
+	}
+	f.add("category = $?", "ai")
+	f.add("created_at BETWEEN $? AND $?", 1, 2)
+	f.add("NOT legal_hold")
+
+	if want := " WHERE category = $1 AND created_at BETWEEN $2 AND $3 AND NOT legal_hold"; f.where() != want {
+		t.Errorf("where() = %q, want %q", f.where(), want)
+	}
+	if want := []any{"ai", 1, 2}; !reflect.DeepEqual(f.args, want) {
+		t.Errorf("args = %v, want %v", f.args, want)
+	}
+}
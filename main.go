@@ -9,7 +9,6 @@ import (
 
 	"github.com/GCET-Open-Source-Foundation/auth"
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool" // Recommended for concurrent use
 )
 
@@ -27,40 +26,140 @@ const (
 var (
 	// Use pgxpool for connection pooling in a real app
 	conn *pgxpool.Pool 
+
+	// Bearer token verification keys (see jwt.go)
+	jwtKeys *jwtKeySet
+
+	// devDummyAuth enables DummyAuthMiddleware instead of bearer tokens.
+	// Never set PF_DEV_DUMMY_AUTH outside local development.
+	devDummyAuth bool
 )
 
 // ---------------------- Router registration ----------------------
 
 func registerRoutes(r *gin.Engine) {
-	// Public routes (no auth required)
-	r.GET("/all", getAllProjects)
+	// Public routes (no auth required, rate limited per client IP)
+	public := r.Group("/", RateLimit("public"))
+	{
+		public.GET("/all", getAllProjects)
+		// Project details; credentials are optional (admins see archive pointers)
+		public.GET("/projects/:id", OptionalAuthMiddleware(), getProjectDetail)
+		// Keyword search (admins may include buffer and deleted projects)
+		public.GET("/projects/search", OptionalAuthMiddleware(), searchProjects)
+		// Edit history
+		public.GET("/projects/:id/revisions", getProjectRevisions)
+		public.GET("/projects/:id/revisions/diff", diffProjectRevisions)
+		public.GET("/projects/:id/status-history", getProjectStatusHistory)
+		// Categories and the tag cloud
+		public.GET("/categories", listCategories)
+		public.GET("/tags", getTagCloud)
+	}
+	authRoutes := r.Group("/auth", RateLimit("auth"))
+	{
+		authRoutes.POST("/register", registerUser)
+		authRoutes.POST("/login", loginUser)
+		authRoutes.POST("/refresh", refreshSession)
+		authRoutes.GET("/oidc/login", startOIDCLogin)
+		authRoutes.GET("/oidc/callback", finishOIDCLogin)
+	}
 
 	// All other routes require at least an authenticated user
-	protected := r.Group("/", DummyAuthMiddleware())
+	// (superadmins may act as another user, see ImpersonationMiddleware)
+	protected := r.Group("/", AuthMiddleware(), SuspensionMiddleware(), ImpersonationMiddleware())
 
 	// --- User routes (RequireRole("user")) ---
 	// "user" is the base role (creator)
-	userRoutes := protected.Group("/", RequireRole("user"))
+	userRoutes := protected.Group("/", RateLimit("user"), RequireRole("user"))
+	{
+		// Profile of the authenticated user
+		userRoutes.GET("/auth/me", getMe)
+		userRoutes.GET("/auth/me/roles", getMyRoles)
+		// Role grants of a user (own grants, or anyone's for superadmins)
+		userRoutes.GET("/users/:id/roles", listUserRoles)
+		// End the current session, or every session with {"all": true}
+		userRoutes.POST("/auth/logout", logout)
+
+		// Personal API keys for scripts and CI
+		userRoutes.POST("/auth/api-keys", createAPIKey)
+		userRoutes.GET("/auth/api-keys", listAPIKeys)
+		userRoutes.DELETE("/auth/api-keys/:id", revokeAPIKey)
+	}
+
+	// User routes needing a scope other than the default projects:read; each
+	// route names its own, so e.g. a submit-only key can submit
+	scopedRoutes := protected.Group("/", RateLimit("user"))
 	{
 		// Submit a project to the buffer
-		userRoutes.POST("/projects/submit", submitProject)
+		scopedRoutes.POST("/projects/submit", RequireRole("user", ScopeProjectsSubmit), submitProject)
+
+		// Project management; who may do what is decided by projectPolicy
+		manage := RequireRole("user", ScopeProjectsManage)
+		scopedRoutes.DELETE("/projects/:id", manage, deleteProject)
+		scopedRoutes.POST("/projects/:id/maintainers", manage, addMaintainer)
+		scopedRoutes.DELETE("/projects/:id/maintainers/:user_id", manage, deleteMaintainer)
+		scopedRoutes.POST("/projects/:id/contributors", manage, AllowContributorHandler)
+		scopedRoutes.DELETE("/projects/:id/contributors/:user_id", manage, RemoveContributorHandler)
+		scopedRoutes.PUT("/projects/:id/status", manage, UpdateProjectStatusHandler)
+		scopedRoutes.PATCH("/projects/:id", manage, editProject)
+		scopedRoutes.POST("/projects/:id/revisions/:rev/revert", manage, revertProjectRevision)
 	}
 
 	// --- Admin routes (RequireRole("admin")) ---
-	adminRoutes := protected.Group("/admin", RequireRole("admin"))
+	adminRoutes := protected.Group("/admin", RateLimit("admin"), RequireRole("admin"))
 	{
 		adminRoutes.GET("/pending", getPendingProjects)
 		adminRoutes.POST("/approve/:id", approveProject)
 		adminRoutes.POST("/reject/:id", rejectProject)
+		// Archive of deleted projects
+		adminRoutes.GET("/deleted", getAllDeletedProjects)
+		adminRoutes.GET("/deleted/:id", getDeletedProject)
+		adminRoutes.POST("/deleted/:id/restore", restoreDeletedProject)
+		// Legal holds exempt records from retention purges
+		adminRoutes.POST("/deleted/:id/legal-hold", legalHoldHandler("deleted_projects", "p_id", true))
+		adminRoutes.DELETE("/deleted/:id/legal-hold", legalHoldHandler("deleted_projects", "p_id", false))
+		adminRoutes.POST("/submissions/:id/legal-hold", legalHoldHandler("buffer_projects", "r_id", true))
+		adminRoutes.DELETE("/submissions/:id/legal-hold", legalHoldHandler("buffer_projects", "r_id", false))
+		// Project edits held for review
+		adminRoutes.GET("/edits", getPendingEdits)
+		adminRoutes.POST("/edits/:id/approve", approveEdit)
+		adminRoutes.POST("/edits/:id/reject", rejectEdit)
+		// Project categories
+		adminRoutes.POST("/categories", createCategory)
+		adminRoutes.DELETE("/categories/:slug", deleteCategory)
 	}
 
 	// --- SuperAdmin routes (RequireRole("superadmin")) ---
-	superadminRoutes := protected.Group("/superadmin", RequireRole("superadmin"))
+	superadminRoutes := protected.Group("/superadmin", RateLimit("superadmin"), RequireRole("superadmin"))
 	{
 		// Create a project directly, bypassing approval
 		superadminRoutes.POST("/create", createProjectAsSuperadmin)
 		// Delete an approved project
 		superadminRoutes.DELETE("/delete/:id", deleteProjectAsSuperadmin)
+		// Inspect any user's effective roles
+		superadminRoutes.GET("/users/:id/effective-roles", getUserEffectiveRoles)
+		superadminRoutes.GET("/users/:id/roles", listUserRoles)
+		// Account suspensions
+		superadminRoutes.POST("/users/:id/suspension", suspendUser)
+		superadminRoutes.DELETE("/users/:id/suspension", liftSuspension)
+		superadminRoutes.GET("/users/:id/suspensions", listUserSuspensions)
+		// Permission cache hit/miss counters
+		superadminRoutes.GET("/metrics/permission-cache", getPermissionCacheStats)
+		// "Act as user" sessions and the audit trail they leave
+		superadminRoutes.POST("/impersonate", startImpersonation)
+		superadminRoutes.GET("/audit-log", getAuditLog)
+		// Role management; never leaves zero superadmins
+		superadminRoutes.GET("/roles/:space", listRoleMembers)
+		superadminRoutes.POST("/roles/superadmin", assignSuperAdmin)
+		superadminRoutes.DELETE("/roles/superadmin", revokeSuperAdmin)
+		superadminRoutes.POST("/roles/admin", assignAdmin)
+		superadminRoutes.DELETE("/roles/admin", revokeAdmin)
+		superadminRoutes.GET("/role-grants/expiring", listExpiringRoleGrants)
+		// Reports of the retention purge job
+		superadminRoutes.GET("/purge-runs", listPurgeRuns)
+		// Role changes that need a second superadmin (two-person rule)
+		superadminRoutes.GET("/role-requests", listRoleChangeRequests)
+		superadminRoutes.POST("/role-requests/:id/confirm", confirmRoleChangeRequest)
+		superadminRoutes.POST("/role-requests/:id/reject", rejectRoleChangeRequest)
 	}
 }
 
@@ -82,14 +181,59 @@ func main() {
 	defer conn.Close()
 	fmt.Println("Connected to project_forum DB")
 
+	// Tables owned by this service (sessions, etc.)
+	if err := ensureSchema(context.Background()); err != nil {
+		log.Fatalf("Schema setup failed: %v\n", err)
+	}
+
 	// Initialize auth (separate auth DB)
 	if err := auth.Init(5432, "postgres", "postgres", "authdb"); err != nil {
 		log.Fatalf("auth.Init failed: %v\n", err)
 	}
 	fmt.Println("auth initialized")
 
+	// Bearer token keys (dummy header auth only with an explicit dev flag)
+	devDummyAuth = os.Getenv("PF_DEV_DUMMY_AUTH") == "1"
+	if jwtKeys, err = loadJWTKeySetFromEnv(); err != nil {
+		if !devDummyAuth {
+			log.Fatalf("JWT key setup failed: %v\n", err)
+		}
+		log.Printf("JWT keys not configured, token issuing disabled: %v\n", err)
+	}
+
+	// Rate limit counters (in memory, or shared through Postgres)
+	if rateStore, err = newRateLimitStoreFromEnv(); err != nil {
+		log.Fatalf("Rate limit setup failed: %v\n", err)
+	}
+
+	// Role hierarchy (optional extensions via PF_ROLE_GRAPH)
+	if err := loadRoleGraph(); err != nil {
+		log.Fatalf("Role graph setup failed: %v\n", err)
+	}
+	// Memberships from before role_grants, needed by the superadmin guards
+	if err := backfillRoleGrants(context.Background()); err != nil {
+		log.Fatalf("Role grant backfill failed: %v\n", err)
+	}
+
+	// Campus single sign-on (optional)
+	if oidcLogin, err = initOIDCFromEnv(context.Background()); err != nil {
+		log.Fatalf("OIDC setup failed: %v\n", err)
+	}
+
+	// Background jobs
+	startJob("role-expiry", roleExpirySweepInterval, sweepExpiredRoleGrants)
+	startJob("project-schedule", projectScheduleInterval, sweepProjectSchedule)
+	startJob("purge", purgeInterval, purgeExpiredRecords)
+	startJob("oidc-states", oidcStateSweepInterval, sweepExpiredOIDCStates)
+	if _, shared := rateStore.(pgRateStore); shared {
+		startJob("rate-buckets", rateBucketSweepInterval, sweepIdleRateBuckets)
+	}
+
 	// Router
 	r := gin.Default()
+	if err := setTrustedProxies(r); err != nil {
+		log.Fatalf("PF_TRUSTED_PROXIES is invalid: %v\n", err)
+	}
 	registerRoutes(r)
 
 	// Start server
@@ -12,6 +12,44 @@ type Project struct {
 	CreatorName string    `json:"creator_name"`
 	StartDate   time.Time `json:"start_date"`
 	Status      string    `json:"status"`
+	Category    *string   `json:"category"` // slug in 'categories'
+	Tags        []string  `json:"tags"`
+	TechStack   []string  `json:"tech_stack"`
+	// Planned end, and the day after it once the scheduler found the
+	// project still open then (see projectschedule.go)
+	EndDate      *time.Time `json:"end_date"`
+	OverdueSince *time.Time `json:"overdue_since"`
+}
+
+// projectColumns selects a Project from 'approved_projects'.
+const projectColumns = `p_id, name, description, creator_id, creator_name, start_date, status, category, tags, tech_stack,
+	end_date, overdue_since`
+
+// Maintainer represents a record in the 'maintainers' table.
+type Maintainer struct {
+	UserID int    `json:"user_id"`
+	MName  string `json:"m_name"`
+}
+
+// Contributor represents a record in the 'contributors' table.
+type Contributor struct {
+	UserID int    `json:"user_id"`
+	CName  string `json:"c_name"`
+}
+
+// UserProfile is the public view of a user from the 'names' table.
+type UserProfile struct {
+	UserID          int    `json:"user_id"`
+	Name            string `json:"name"`
+	ProjectsCreated int    `json:"projects_created"` // approved projects only
+}
+
+// ProjectDetail is a Project with the people working on it.
+type ProjectDetail struct {
+	Project
+	Creator      UserProfile   `json:"creator"`
+	Maintainers  []Maintainer  `json:"maintainers"`
+	Contributors []Contributor `json:"contributors"`
 }
 
 // BufferProject represents a record in the 'buffer_projects' table.
@@ -23,15 +61,55 @@ type BufferProject struct {
 	CreatorName string    `json:"creator_name"`
 	Status      string    `json:"status"`
 	SubmittedAt time.Time `json:"submitted_at"`
+	Category    *string   `json:"category"`
+	Tags        []string  `json:"tags"`
+	TechStack   []string  `json:"tech_stack"`
+	// Dates proposed by the creator; approvers may adjust them
+	PlannedStart *time.Time `json:"planned_start"`
+	PlannedEnd   *time.Time `json:"planned_end"`
+	RejectedAt   *time.Time `json:"rejected_at"`
+	LegalHold    bool       `json:"legal_hold"` // exempt from purging (see retention.go)
 }
 
 // createProjectReq is the JSON body for submitting or creating a project.
 type createProjectReq struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description" binding:"required"`
+	Name         string   `json:"name" binding:"required"`
+	Description  string   `json:"description" binding:"required"`
+	Category     string   `json:"category"` // optional category slug
+	Tags         []string `json:"tags"`
+	TechStack    []string `json:"tech_stack"`
+	plannedDates          // optional start_date and end_date
 	// CreatorID is now read from the auth context, not the body.
 }
 
+// superadminCreateReq is the JSON body for POST /superadmin/create, which
+// names the creator instead of taking it from the auth context.
+type superadminCreateReq struct {
+	createProjectReq
+	CreatorID int `json:"creator_id" binding:"required"`
+}
+
+// RoleGrant represents a record in the 'role_grants' table, with the display name.
+type RoleGrant struct {
+	UserID    int        `json:"user_id"`
+	UserName  string     `json:"user_name"`
+	Space     string     `json:"space"`
+	GrantedAt time.Time  `json:"granted_at"`
+	ExpiresAt *time.Time `json:"expires_at"` // nil for permanent grants
+}
+
+// assignUserReq is the JSON body for granting a role.
+type assignUserReq struct {
+	UserID    int        `json:"user_id" binding:"required"`
+	UserName  string     `json:"user_name"`  // Required, keeps 'names' populated
+	ExpiresAt *time.Time `json:"expires_at"` // Optional, the grant is revoked then
+}
+
+// revokeUserReq is the JSON body for revoking a role.
+type revokeUserReq struct {
+	UserID int `json:"user_id" binding:"required"`
+}
+
 // roleChangeReq is the JSON body for assigning/revoking roles.
 type roleChangeReq struct {
 	UserID   int    `json:"user_id" binding:"required"`
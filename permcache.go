@@ -0,0 +1,143 @@
+// permcache.go
+package main
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/GCET-Open-Source-Foundation/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// ---------------------- Permission lookup cache ----------------------
+
+// permKey identifies one auth.Check_permissions question.
+type permKey struct {
+	user, space, role string
+}
+
+type permEntry struct {
+	allowed bool
+	expires time.Time
+}
+
+// permCache is a bounded TTL cache of auth.Check_permissions answers.
+// Changes made through AssignMemberToSpace / RemoveMemberFromSpace on this
+// instance invalidate it immediately; other instances see them after the TTL.
+type permCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	max     int
+	entries map[permKey]permEntry
+
+	hits, misses, memoHits, evictions atomic.Int64
+}
+
+// permissionCache is sized by PF_PERM_CACHE_SIZE and PF_PERM_CACHE_TTL.
+var permissionCache = newPermCache(
+	envInt("PF_PERM_CACHE_SIZE", 10000),
+	envDuration("PF_PERM_CACHE_TTL", 30*time.Second),
+)
+
+func newPermCache(max int, ttl time.Duration) *permCache {
+	return &permCache{ttl: ttl, max: max, entries: map[permKey]permEntry{}}
+}
+
+func (pc *permCache) get(k permKey) (bool, bool) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	e, ok := pc.entries[k]
+	if !ok || time.Now().After(e.expires) {
+		pc.misses.Add(1)
+		return false, false
+	}
+	pc.hits.Add(1)
+	return e.allowed, true
+}
+
+func (pc *permCache) put(k permKey, allowed bool) {
+	if pc.max <= 0 || pc.ttl <= 0 {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if len(pc.entries) >= pc.max {
+		// Drop expired entries first, then arbitrary ones until there is room.
+		now := time.Now()
+		for key, e := range pc.entries {
+			if now.After(e.expires) {
+				delete(pc.entries, key)
+				pc.evictions.Add(1)
+			}
+		}
+		for key := range pc.entries {
+			if len(pc.entries) < pc.max {
+				break
+			}
+			delete(pc.entries, key)
+			pc.evictions.Add(1)
+		}
+	}
+	pc.entries[k] = permEntry{allowed: allowed, expires: time.Now().Add(pc.ttl)}
+}
+
+// invalidate forgets every cached answer about userIDStr in space.
+func (pc *permCache) invalidate(userIDStr, space string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for k := range pc.entries {
+		if k.user == userIDStr && k.space == space {
+			delete(pc.entries, k)
+		}
+	}
+}
+
+// checkPermission is auth.Check_permissions behind the TTL cache and, when c is
+// not nil, a per-request memo so one request never asks the auth DB twice.
+func checkPermission(c *gin.Context, userIDStr, space, role string) bool {
+	k := permKey{userIDStr, space, role}
+
+	var memo map[permKey]bool
+	if c != nil {
+		if v, ok := c.Get("perm_memo"); ok {
+			memo = v.(map[permKey]bool)
+		} else {
+			memo = map[permKey]bool{}
+			c.Set("perm_memo", memo)
+		}
+		if allowed, ok := memo[k]; ok {
+			permissionCache.memoHits.Add(1)
+			return allowed
+		}
+	}
+
+	allowed, ok := permissionCache.get(k)
+	if !ok {
+		allowed = auth.Check_permissions(userIDStr, space, role)
+		permissionCache.put(k, allowed)
+	}
+	if memo != nil {
+		memo[k] = allowed
+	}
+	return allowed
+}
+
+// GET /superadmin/metrics/permission-cache - cache hit/miss counters
+func getPermissionCacheStats(c *gin.Context) {
+	permissionCache.mu.Lock()
+	size := len(permissionCache.entries)
+	permissionCache.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"hits":        permissionCache.hits.Load(),
+		"misses":      permissionCache.misses.Load(),
+		"memo_hits":   permissionCache.memoHits.Load(),
+		"evictions":   permissionCache.evictions.Load(),
+		"size":        size,
+		"max_entries": permissionCache.max,
+		"ttl_seconds": permissionCache.ttl.Seconds(),
+	})
+}
@@ -0,0 +1,162 @@
+// policy.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------------------- Project authorization policy ----------------------
+
+// Relations a user can have to a project.
+const (
+	RelSuperadmin  = "superadmin"
+	RelAdmin       = "admin"
+	RelCreator     = "creator"
+	RelMaintainer  = "maintainer"
+	RelContributor = "contributor"
+)
+
+// Project-scoped actions.
+const (
+	ActionDeleteProject     = "project:delete"
+	ActionAddMaintainer     = "project:maintainers:add"
+	ActionRemoveMaintainer  = "project:maintainers:remove"
+	ActionAddContributor    = "project:contributors:add"
+	ActionRemoveContributor = "project:contributors:remove"
+	ActionUpdateStatus      = "project:status:update"
+	ActionEditProject       = "project:edit"
+	ActionRevertProject     = "project:revisions:revert"
+)
+
+// projectPolicy lists, per action, the relations that may perform it.
+// This table is the single source of truth for project permissions.
+var projectPolicy = map[string][]string{
+	ActionDeleteProject:     {RelSuperadmin, RelAdmin, RelCreator},
+	ActionAddMaintainer:     {RelSuperadmin, RelAdmin, RelCreator},
+	ActionRemoveMaintainer:  {RelSuperadmin, RelAdmin, RelCreator},
+	ActionAddContributor:    {RelSuperadmin, RelAdmin, RelCreator, RelMaintainer},
+	ActionRemoveContributor: {RelSuperadmin, RelAdmin, RelCreator, RelMaintainer},
+	ActionUpdateStatus:      {RelSuperadmin, RelAdmin, RelCreator, RelMaintainer},
+	ActionEditProject:       {RelSuperadmin, RelAdmin, RelCreator, RelMaintainer},
+	ActionRevertProject:     {RelSuperadmin, RelAdmin, RelMaintainer},
+}
+
+// errProjectNotFound is returned by Can when the approved project does not exist.
+var errProjectNotFound = errors.New("project not found")
+
+// Decision is the outcome of a policy check, with an explanation.
+type Decision struct {
+	Allowed   bool     `json:"allowed"`
+	Action    string   `json:"action"`
+	Relations []string `json:"relations"` // what the user is to the project
+	Reason    string   `json:"reason"`
+}
+
+// Can decides whether userID may perform action on approved project pid.
+func Can(userID int, action string, pid int) (Decision, error) {
+	return canFor(nil, userID, action, pid)
+}
+
+// canFor is Can with role lookups memoized on the request c (may be nil).
+func canFor(c *gin.Context, userID int, action string, pid int) (Decision, error) {
+	d := Decision{Action: action}
+	allowed, ok := projectPolicy[action]
+	if !ok {
+		d.Reason = fmt.Sprintf("unknown action %q", action)
+		return d, nil
+	}
+
+	rels, err := projectRelations(c, userID, pid)
+	if err != nil {
+		return d, err
+	}
+	d.Relations = rels
+
+	for _, have := range rels {
+		if slices.Contains(allowed, have) {
+			d.Allowed = true
+			d.Reason = fmt.Sprintf("allowed as %s", have)
+			return d, nil
+		}
+	}
+
+	held := "no relation to this project"
+	if len(rels) > 0 {
+		held = "only " + strings.Join(rels, ", ")
+	}
+	d.Reason = fmt.Sprintf("%s requires one of: %s; user is %s",
+		action, strings.Join(allowed, ", "), held)
+	return d, nil
+}
+
+// projectRelations returns every relation userID has to project pid.
+func projectRelations(c *gin.Context, userID, pid int) ([]string, error) {
+	var creatorID int
+	var isMaintainer, isContributor bool
+	err := conn.QueryRow(context.Background(),
+		`SELECT creator_id,
+                EXISTS(SELECT 1 FROM maintainers WHERE p_id = $1 AND user_id = $2),
+                EXISTS(SELECT 1 FROM contributors WHERE p_id = $1 AND user_id = $2)
+         FROM approved_projects WHERE p_id = $1`,
+		pid, userID).Scan(&creatorID, &isMaintainer, &isContributor)
+	if err == pgx.ErrNoRows {
+		return nil, errProjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load project relations: %w", err)
+	}
+
+	var rels []string
+	userIDStr := uidToStr(userID)
+	if hasRoleFor(c, userIDStr, "superadmin") {
+		rels = append(rels, RelSuperadmin)
+	}
+	if hasRoleFor(c, userIDStr, "admin") {
+		rels = append(rels, RelAdmin)
+	}
+	if creatorID == userID {
+		rels = append(rels, RelCreator)
+	}
+	if isMaintainer {
+		rels = append(rels, RelMaintainer)
+	}
+	if isContributor {
+		rels = append(rels, RelContributor)
+	}
+	return rels, nil
+}
+
+// authorizeProject runs Can for the authenticated user and writes the
+// 401/403/404/500 response itself when the request may not proceed.
+func authorizeProject(c *gin.Context, action string, pid int) bool {
+	userID, ok := getUserID(c)
+	if !ok {
+		respondErr(c, http.StatusUnauthorized, "invalid user ID in context", nil)
+		return false
+	}
+
+	d, err := canFor(c, userID, action, pid)
+	if err == errProjectNotFound {
+		respondErr(c, http.StatusNotFound, "project not found", nil)
+		return false
+	}
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to check project permissions", err)
+		return false
+	}
+	if !d.Allowed {
+		log.Printf("Denied: user %d: %s\n", userID, d.Reason)
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied", "reason": d.Reason})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+// projectschedule.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ---------------------- Planned project dates ----------------------
+
+// projectScheduleInterval is how often projects are started and checked for
+// being overdue (PF_PROJECT_SCHEDULE_SWEEP, default 15m; 0 disables it).
+var projectScheduleInterval = envDuration("PF_PROJECT_SCHEDULE_SWEEP", 15*time.Minute)
+
+// sqlToday is today() in SQL: the UTC date, whatever the session's time zone.
+const sqlToday = `(now() AT TIME ZONE 'UTC')::date`
+
+// sweepProjectSchedule moves upcoming projects whose start date has come to
+// in_progress, and flags projects still open after their end date as overdue
+// (clearing the flag once that no longer holds).
+func sweepProjectSchedule(ctx context.Context) error {
+	// 1. Start projects, one at a time so each gets its history line
+	rows, err := conn.Query(ctx,
+		`SELECT p_id FROM approved_projects WHERE status=$1 AND start_date <= `+sqlToday+` ORDER BY p_id`,
+		StatusUpcoming)
+	if err != nil {
+		return fmt.Errorf("load projects to start: %w", err)
+	}
+	var pids []int
+	for rows.Next() {
+		var pid int
+		if err := rows.Scan(&pid); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan projects to start: %w", err)
+		}
+		pids = append(pids, pid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("load projects to start: %w", err)
+	}
+	for _, pid := range pids {
+		_, err := changeProjectStatus(ctx, pid, StatusInProgress, 0, "start date reached")
+		// Someone else moved or deleted it since; nothing to do
+		if err == errIllegalTransition || err == errProjectNotFound {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error: start project %d: %v\n", pid, err)
+		}
+	}
+
+	// 2. Overdue flags
+	tag, err := conn.Exec(ctx,
+		`UPDATE approved_projects SET overdue_since=end_date + 1
+         WHERE overdue_since IS NULL AND end_date < `+sqlToday+` AND status = ANY($1)`,
+		openProjectStatuses)
+	if err != nil {
+		return fmt.Errorf("flag overdue projects: %w", err)
+	}
+	if n := tag.RowsAffected(); n > 0 {
+		log.Printf("Flagged %d project(s) as overdue\n", n)
+	}
+	if _, err := conn.Exec(ctx,
+		`UPDATE approved_projects SET overdue_since=NULL
+         WHERE overdue_since IS NOT NULL
+           AND (end_date IS NULL OR end_date >= `+sqlToday+` OR NOT status = ANY($1))`,
+		openProjectStatuses); err != nil {
+		return fmt.Errorf("clear overdue flags: %w", err)
+	}
+	return nil
+}
+
+// openProjectStatuses are the statuses in which a project can be overdue.
+var openProjectStatuses = []string{StatusUpcoming, StatusInProgress, StatusOnHold}
+
+// plannedDates is the optional {start_date, end_date} (YYYY-MM-DD) of a
+// project submission or approval.
+type plannedDates struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// parse returns the dates (nil when not given), writing the 400 itself.
+// notBefore, if set, is the earliest allowed start date.
+func (d plannedDates) parse(c *gin.Context, notBefore *time.Time) (start, end *time.Time, ok bool) {
+	for _, f := range []struct {
+		name string
+		v    string
+		dst  **time.Time
+	}{{"start_date", d.StartDate, &start}, {"end_date", d.EndDate, &end}} {
+		if f.v == "" {
+			continue
+		}
+		t, err := time.Parse(dateKeyLayout, f.v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": f.name + " must be a date (YYYY-MM-DD)"})
+			return nil, nil, false
+		}
+		*f.dst = &t
+	}
+	if start != nil && notBefore != nil && start.Before(*notBefore) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date cannot be in the past"})
+		return nil, nil, false
+	}
+	if start != nil && end != nil && end.Before(*start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date cannot be before start_date"})
+		return nil, nil, false
+	}
+	return start, end, true
+}
+
+// today is the current UTC date, as compared with start dates.
+func today() time.Time {
+	return utcDate(time.Now())
+}
+
+// utcDate returns midnight UTC of the UTC date at t.
+func utcDate(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// endDateKey is the end_date sort key of GET /all; projects without an end
+// date sort last.
+func endDateKey(t *time.Time) string {
+	if t == nil {
+		return "infinity"
+	}
+	return t.Format(dateKeyLayout)
+}
@@ -0,0 +1,59 @@
+// projectschedule_test.go
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUTCDate(t *testing.T) {
+	// Far from UTC on both sides, the local date differs for part of every day
+	east, west := time.FixedZone("UTC+14", 14*3600), time.FixedZone("UTC-12", -12*3600)
+	tests := []struct {
+		name string
+		in   time.Time
+		want string
+	}{
+		{"utc", time.Date(2025, 3, 9, 12, 0, 0, 0, time.UTC), "2025-03-09"},
+		{"east, local date ahead", time.Date(2025, 3, 10, 9, 0, 0, 0, east), "2025-03-09"},
+		{"west, local date behind", time.Date(2025, 3, 9, 20, 0, 0, 0, west), "2025-03-10"},
+		{"east, same date", time.Date(2025, 3, 10, 20, 0, 0, 0, east), "2025-03-10"},
+		{"new year", time.Date(2025, 1, 1, 1, 0, 0, 0, east), "2024-12-31"},
+	}
+	for _, tt := range tests {
+		got := utcDate(tt.in)
+		if got.Location() != time.UTC || !got.Equal(got.Truncate(24*time.Hour)) {
+			t.Errorf("%s: utcDate = %v, want midnight UTC", tt.name, got)
+		}
+		if d := got.Format(dateKeyLayout); d != tt.want {
+			t.Errorf("%s: utcDate = %s, want %s", tt.name, d, tt.want)
+		}
+	}
+}
+
+func TestEndDateKey(t *testing.T) {
+	date := func(s string) *time.Time {
+		d, err := time.Parse(dateKeyLayout, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &d
+	}
+	late := time.Date(2025, 3, 9, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   *time.Time
+		want string
+	}{
+		{"no end date", nil, "infinity"},
+		{"date", date("2025-03-09"), "2025-03-09"},
+		{"time of day is dropped", &late, "2025-03-09"},
+		{"leap day", date("2024-02-29"), "2024-02-29"},
+	}
+	for _, tt := range tests {
+		if got := endDateKey(tt.in); got != tt.want {
+			t.Errorf("%s: endDateKey = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// ratelimit.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ---------------------- Rate limiting ----------------------
+
+// rateBudget is a token bucket: Burst tokens, refilled at PerMinute per minute.
+type rateBudget struct {
+	PerMinute float64
+	Burst     int
+}
+
+// defaultRateBudgets per route group; each can be overridden with
+// PF_RATE_<GROUP>="<per minute>:<burst>", e.g. PF_RATE_PUBLIC="120:60".
+var defaultRateBudgets = map[string]rateBudget{
+	"public":     {PerMinute: 60, Burst: 30},
+	"auth":       {PerMinute: 10, Burst: 10}, // login/register/refresh
+	"user":       {PerMinute: 120, Burst: 60},
+	"admin":      {PerMinute: 300, Burst: 100},
+	"superadmin": {PerMinute: 600, Burst: 200},
+}
+
+// rateTake is the outcome of taking one token from a bucket.
+type rateTake struct {
+	Allowed bool
+	Tokens  float64 // tokens left after this request
+}
+
+// rateLimitStore keeps the buckets. Use the Postgres store to share
+// counters between several instances.
+type rateLimitStore interface {
+	Take(ctx context.Context, key string, b rateBudget) (rateTake, error)
+}
+
+// rateStore is chosen at startup by PF_RATE_LIMIT_STORE ("memory" or "postgres").
+var rateStore rateLimitStore = newMemoryRateStore()
+
+// newRateLimitStoreFromEnv returns the store selected by PF_RATE_LIMIT_STORE.
+func newRateLimitStoreFromEnv() (rateLimitStore, error) {
+	switch os.Getenv("PF_RATE_LIMIT_STORE") {
+	case "", "memory":
+		return newMemoryRateStore(), nil
+	case "postgres":
+		return pgRateStore{}, nil
+	default:
+		return nil, fmt.Errorf("unknown PF_RATE_LIMIT_STORE %q", os.Getenv("PF_RATE_LIMIT_STORE"))
+	}
+}
+
+// rateBudgetFor returns the budget of group, honouring PF_RATE_<GROUP>.
+func rateBudgetFor(group string) rateBudget {
+	b := defaultRateBudgets[group]
+	env := "PF_RATE_" + strings.ToUpper(group)
+	if v := os.Getenv(env); v != "" {
+		perMin, burst, _ := strings.Cut(v, ":")
+		pm, err1 := strconv.ParseFloat(perMin, 64)
+		bu, err2 := strconv.Atoi(burst)
+		if err1 != nil || err2 != nil || pm <= 0 || bu <= 0 {
+			log.Printf("Warning: invalid %s=%q, using %v/min burst %d\n", env, v, b.PerMinute, b.Burst)
+		} else {
+			b = rateBudget{PerMinute: pm, Burst: bu}
+		}
+	}
+	return b
+}
+
+// setTrustedProxies tells r which peers may set X-Forwarded-For / X-Real-IP,
+// from the comma-separated IPs or CIDRs in PF_TRUSTED_PROXIES. By default no
+// peer is trusted and ClientIP is the connection's address, so clients cannot
+// pick their own rate limit bucket.
+func setTrustedProxies(r *gin.Engine) error {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("PF_TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return r.SetTrustedProxies(proxies)
+}
+
+// RateLimit enforces group's budget per authenticated user, or per client IP
+// for anonymous requests, and sets RateLimit-* / Retry-After headers.
+// Client IPs come from forwarding headers only behind a trusted proxy
+// (see setTrustedProxies).
+// If the store is unavailable requests are let through (fail open).
+func RateLimit(group string) gin.HandlerFunc {
+	budget := rateBudgetFor(group)
+	perSecond := budget.PerMinute / 60
+
+	return func(c *gin.Context) {
+		key := group + ":ip:" + c.ClientIP()
+		if uid, ok := getUserID(c); ok {
+			key = group + ":user:" + uidToStr(uid)
+		}
+
+		res, err := rateStore.Take(context.Background(), key, budget)
+		if err != nil {
+			log.Printf("Error: rate limit store: %v\n", err)
+			c.Next()
+			return
+		}
+
+		reset := math.Ceil((float64(budget.Burst) - res.Tokens) / perSecond)
+		c.Header("RateLimit-Limit", strconv.Itoa(budget.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(res.Tokens)))))
+		c.Header("RateLimit-Reset", strconv.Itoa(int(math.Max(0, reset))))
+
+		if !res.Allowed {
+			retry := math.Ceil((1 - res.Tokens) / perSecond)
+			c.Header("Retry-After", strconv.Itoa(int(math.Max(1, retry))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// --- In-memory store (single instance) ---
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type memoryRateStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+}
+
+func newMemoryRateStore() *memoryRateStore {
+	return &memoryRateStore{buckets: map[string]*memoryBucket{}}
+}
+
+func (s *memoryRateStore) Take(_ context.Context, key string, b rateBudget) (rateTake, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if len(s.buckets) > 100000 {
+		s.pruneIdle(now)
+	}
+
+	bk, ok := s.buckets[key]
+	if !ok {
+		bk = &memoryBucket{tokens: float64(b.Burst), updated: now}
+		s.buckets[key] = bk
+	}
+	bk.tokens = math.Min(float64(b.Burst), bk.tokens+now.Sub(bk.updated).Minutes()*b.PerMinute)
+	bk.updated = now
+
+	if bk.tokens < 1 {
+		return rateTake{Allowed: false, Tokens: bk.tokens}, nil
+	}
+	bk.tokens--
+	return rateTake{Allowed: true, Tokens: bk.tokens}, nil
+}
+
+// pruneIdle drops buckets untouched for an hour; with any sane budget they
+// have refilled completely and carry no state.
+func (s *memoryRateStore) pruneIdle(now time.Time) {
+	for k, bk := range s.buckets {
+		if now.Sub(bk.updated) > time.Hour {
+			delete(s.buckets, k)
+		}
+	}
+}
+
+// --- Postgres store (shared between instances) ---
+
+type pgRateStore struct{}
+
+// pgRefill is the bucket level after refilling since the last request
+// ($2 = burst, $3 = tokens per minute).
+const pgRefill = `LEAST($2::float8, rb.tokens + EXTRACT(EPOCH FROM now() - rb.updated_at)::float8 / 60 * $3::float8)`
+
+// pgRateBucketIdle is how long a bucket may go unused before the sweeper
+// deletes it; like memoryRateStore.pruneIdle, it has refilled long before.
+const pgRateBucketIdle = time.Hour
+
+// Take refills and decrements the bucket in one atomic upsert. All SET
+// expressions see the old row, so "allowed" is decided on the refilled level.
+func (pgRateStore) Take(ctx context.Context, key string, b rateBudget) (rateTake, error) {
+	var res rateTake
+	err := conn.QueryRow(ctx,
+		`INSERT INTO rate_limit_buckets AS rb (key, tokens, allowed, updated_at)
+         VALUES ($1, $2::float8 - 1, true, now())
+         ON CONFLICT (key) DO UPDATE SET
+             tokens = CASE WHEN `+pgRefill+` >= 1 THEN `+pgRefill+` - 1 ELSE `+pgRefill+` END,
+             allowed = `+pgRefill+` >= 1,
+             updated_at = now()
+         RETURNING allowed, tokens`,
+		key, float64(b.Burst), b.PerMinute).Scan(&res.Allowed, &res.Tokens)
+	return res, err
+}
@@ -0,0 +1,68 @@
+// ratelimit_test.go
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitClientIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		proxies  string // PF_TRUSTED_PROXIES
+		peer     string
+		spoofed  string // X-Forwarded-For on the request after the bucket is empty
+		wantCode int
+	}{
+		{"untrusted peer cannot pick a new bucket", "", "203.0.113.7:4000", "198.51.100.1", http.StatusTooManyRequests},
+		{"peer outside the trusted list", "10.0.0.0/8", "203.0.113.7:4000", "198.51.100.1", http.StatusTooManyRequests},
+		{"trusted proxy forwards the client", "10.0.0.0/8", "10.1.2.3:4000", "198.51.100.1", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PF_TRUSTED_PROXIES", tt.proxies)
+			t.Setenv("PF_RATE_PUBLIC", "1:2")
+			prev := rateStore
+			rateStore = newMemoryRateStore()
+			t.Cleanup(func() { rateStore = prev })
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			if err := setTrustedProxies(r); err != nil {
+				t.Fatal(err)
+			}
+			r.GET("/ping", RateLimit("public"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			get := func(forwardedFor string) int {
+				req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+				req.RemoteAddr = tt.peer
+				if forwardedFor != "" {
+					req.Header.Set("X-Forwarded-For", forwardedFor)
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Code
+			}
+
+			// Use up the burst of whoever the first two requests count as
+			for i := 0; i < 2; i++ {
+				if code := get("192.0.2.10"); code != http.StatusOK {
+					t.Fatalf("request %d: status %d, want 200", i+1, code)
+				}
+			}
+			if code := get(tt.spoofed); code != tt.wantCode {
+				t.Fatalf("with X-Forwarded-For %s: status %d, want %d", tt.spoofed, code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestSetTrustedProxiesInvalid(t *testing.T) {
+	t.Setenv("PF_TRUSTED_PROXIES", "10.0.0.0/8, not-an-ip")
+	if err := setTrustedProxies(gin.New()); err == nil {
+		t.Fatal("want an error for an invalid proxy address")
+	}
+}
@@ -0,0 +1,202 @@
+// retention.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------------------- Retention and hard purge ----------------------
+
+// purgeInterval is how often expired records are purged (PF_PURGE_INTERVAL,
+// default 1h; 0 disables the job). Each run deletes at most purgeBatchSize
+// rows per statement (PF_PURGE_BATCH, default 500) until nothing is left.
+var (
+	purgeInterval  = envDuration("PF_PURGE_INTERVAL", time.Hour)
+	purgeBatchSize = envInt("PF_PURGE_BATCH", 500)
+)
+
+// retentionPolicy says how long rows of one table are kept. Rows with
+// legal_hold set are never purged.
+type retentionPolicy struct {
+	table     string
+	idColumn  string
+	retention time.Duration // 0 keeps rows forever
+	expired   string        // SQL condition, $1 is the cutoff time
+	related   []string      // tables whose rows for a purged id go too
+}
+
+// retentionPolicies are read from PF_RETENTION_REJECTED (rejected
+// submissions, counted from rejection) and PF_RETENTION_DELETED (archived
+// projects, counted from deletion), e.g. "2160h" for 90 days. Both default
+// to keeping everything.
+var retentionPolicies = []retentionPolicy{
+	{
+		table:     "buffer_projects",
+		idColumn:  "r_id",
+		retention: envDuration("PF_RETENTION_REJECTED", 0),
+		// Rows rejected before rejected_at existed count from submission
+		expired: `status = 'rejected' AND COALESCE(rejected_at, submitted_at) < $1`,
+	},
+	{
+		table:     "deleted_projects",
+		idColumn:  "p_id",
+		retention: envDuration("PF_RETENTION_DELETED", 0),
+		expired:   `deleted_date < $1`,
+		related:   []string{"project_revisions", "project_status_history", "project_edit_requests"},
+	},
+}
+
+// PurgeRun represents a record in the 'purge_runs' table: what one run of
+// the purge job removed from one table.
+type PurgeRun struct {
+	ID         int64     `json:"id"`
+	TableName  string    `json:"table_name"`
+	Cutoff     time.Time `json:"cutoff"`
+	Purged     int       `json:"purged"`
+	PurgedIDs  []int     `json:"purged_ids"`
+	Batches    int       `json:"batches"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      *string   `json:"error"`
+}
+
+// purgeExpiredRecords applies every retention policy and records a
+// purge_runs row for each table it removed something from (or failed on).
+func purgeExpiredRecords(ctx context.Context) error {
+	for _, p := range retentionPolicies {
+		if p.retention <= 0 {
+			continue
+		}
+		started := time.Now()
+		cutoff := started.Add(-p.retention)
+		ids, batches, err := purgeTable(ctx, p, cutoff)
+		if len(ids) == 0 && err == nil {
+			continue
+		}
+
+		var errText *string
+		if err != nil {
+			s := err.Error()
+			errText = &s
+			log.Printf("Error: purge %s: %v\n", p.table, err)
+		}
+		log.Printf("Purged %d row(s) from %s older than %s\n", len(ids), p.table, cutoff.Format(time.RFC3339))
+		if _, err := conn.Exec(ctx,
+			`INSERT INTO purge_runs (table_name, cutoff, purged, purged_ids, batches, started_at, error)
+             VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			p.table, cutoff, len(ids), ids, batches, started, errText); err != nil {
+			return fmt.Errorf("record purge of %s: %w", p.table, err)
+		}
+	}
+	return nil
+}
+
+// purgeTable deletes the expired rows of p's table batch by batch, each in
+// its own transaction, and returns the IDs removed so far even on error.
+func purgeTable(ctx context.Context, p retentionPolicy, cutoff time.Time) ([]int, int, error) {
+	purged := []int{}
+	batches := 0
+	for {
+		ids, err := purgeBatch(ctx, p, cutoff)
+		if err != nil {
+			return purged, batches, err
+		}
+		if len(ids) == 0 {
+			return purged, batches, nil
+		}
+		batches++
+		purged = append(purged, ids...)
+		if len(ids) < purgeBatchSize {
+			return purged, batches, nil
+		}
+	}
+}
+
+func purgeBatch(ctx context.Context, p retentionPolicy, cutoff time.Time) ([]int, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(
+		`DELETE FROM %[1]s WHERE %[2]s IN (
+             SELECT %[2]s FROM %[1]s WHERE NOT legal_hold AND %[3]s
+             ORDER BY %[2]s LIMIT $2 FOR UPDATE SKIP LOCKED)
+         RETURNING %[2]s`, p.table, p.idColumn, p.expired),
+		cutoff, purgeBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range p.related {
+		if _, err := tx.Exec(ctx, `DELETE FROM `+t+` WHERE p_id = ANY($1)`, ids); err != nil {
+			return nil, fmt.Errorf("purge %s: %w", t, err)
+		}
+	}
+	return ids, tx.Commit(ctx)
+}
+
+// GET /superadmin/purge-runs?limit=&offset= - What the purge job removed, newest first
+func listPurgeRuns(c *gin.Context) {
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	rows, err := conn.Query(context.Background(),
+		`SELECT id, table_name, cutoff, purged, purged_ids, batches, started_at, finished_at, error
+         FROM purge_runs ORDER BY id DESC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch purge runs", err)
+		return
+	}
+	out, err := pgx.CollectRows(rows, pgx.RowToStructByPos[PurgeRun])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": out, "limit": limit, "offset": offset})
+}
+
+// legalHoldHandler returns the handler for
+// POST (hold=true) or DELETE (hold=false) /admin/<resource>/:id/legal-hold,
+// which exempts a row of table from purging, or makes it purgeable again.
+func legalHoldHandler(table, idColumn string, hold bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := getIntParam(c, "id")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		actorID, _ := getActorID(c)
+
+		cmdTag, err := conn.Exec(context.Background(),
+			`UPDATE `+table+` SET legal_hold=$2 WHERE `+idColumn+`=$1`, id, hold)
+		if err != nil {
+			respondErr(c, http.StatusInternalServerError, "failed to update legal hold", err)
+			return
+		}
+		if cmdTag.RowsAffected() == 0 {
+			respondErr(c, http.StatusNotFound, "record not found", nil)
+			return
+		}
+
+		action := "legal_hold_lifted"
+		if hold {
+			action = "legal_hold_set"
+		}
+		recordAudit(context.Background(), actorID, 0, action, fmt.Sprintf("%s %s=%d", table, idColumn, id))
+		c.JSON(http.StatusOK, gin.H{idColumn: id, "legal_hold": hold})
+	}
+}
@@ -0,0 +1,98 @@
+// roleexpiry.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------------------- Expiring role grants ----------------------
+
+// roleExpirySweepInterval is how often expired grants are revoked
+// (PF_ROLE_EXPIRY_SWEEP, default 1m; 0 disables the sweeper).
+var roleExpirySweepInterval = envDuration("PF_ROLE_EXPIRY_SWEEP", time.Minute)
+
+// sweepExpiredRoleGrants revokes every grant whose expires_at has passed and
+// records each revocation in the audit log. The last superadmin is kept even
+// if their grant expired; that is logged on every sweep until someone acts.
+func sweepExpiredRoleGrants(ctx context.Context) error {
+	rows, err := conn.Query(ctx,
+		`SELECT user_id, space, expires_at FROM role_grants
+         WHERE expires_at <= now() ORDER BY expires_at`)
+	if err != nil {
+		return fmt.Errorf("load expired grants: %w", err)
+	}
+	type expired struct {
+		UserID    int
+		Space     string
+		ExpiresAt time.Time
+	}
+	grants, err := pgx.CollectRows(rows, pgx.RowToStructByPos[expired])
+	if err != nil {
+		return fmt.Errorf("scan expired grants: %w", err)
+	}
+
+	for _, g := range grants {
+		err := RemoveMemberFromSpace(g.UserID, g.Space)
+		if errors.Is(err, errLastSuperadmin) {
+			log.Printf("Warning: superadmin grant of user %d expired at %s but is the last one, keeping it\n",
+				g.UserID, g.ExpiresAt.Format(time.RFC3339))
+			continue
+		}
+		if err != nil {
+			log.Printf("Error: revoke expired %s grant of user %d: %v\n", g.Space, g.UserID, err)
+			continue
+		}
+		recordAudit(ctx, 0, g.UserID, "role_expired",
+			fmt.Sprintf("%s grant expired at %s", g.Space, g.ExpiresAt.Format(time.RFC3339)))
+	}
+	return nil
+}
+
+// GET /superadmin/role-grants/expiring?within=72h&limit=&offset=
+// Grants that expire within the given window, soonest first.
+func listExpiringRoleGrants(c *gin.Context) {
+	within, err := time.ParseDuration(c.DefaultQuery("within", "72h"))
+	if err != nil || within <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "within must be a positive duration such as 72h"})
+		return
+	}
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	until := time.Now().Add(within)
+	ctx := context.Background()
+
+	var total int
+	if err := conn.QueryRow(ctx,
+		`SELECT count(*) FROM role_grants WHERE expires_at <= $1`, until).Scan(&total); err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to count expiring grants", err)
+		return
+	}
+
+	rows, err := conn.Query(ctx,
+		`SELECT g.user_id, COALESCE(n.name, ''), g.space, g.granted_at, g.expires_at
+         FROM role_grants g LEFT JOIN names n ON n.id = g.user_id
+         WHERE g.expires_at <= $1
+         ORDER BY g.expires_at, g.user_id LIMIT $2 OFFSET $3`,
+		until, limit, offset)
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "failed to fetch expiring grants", err)
+		return
+	}
+	grants, err := pgx.CollectRows(rows, pgx.RowToStructByPos[RoleGrant])
+	if err != nil {
+		respondErr(c, http.StatusInternalServerError, "scan failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": grants, "total": total, "limit": limit, "offset": offset, "until": until})
+}
@@ -0,0 +1,172 @@
+// roles.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ---------------------- Role graph ----------------------
+
+// roleDef declares a role: the auth space whose members hold it directly,
+// and the roles it includes. A role with no space is held by every
+// authenticated user.
+type roleDef struct {
+	Space    string   `json:"space"`
+	Includes []string `json:"includes"`
+}
+
+// defaultRoleGraph is superadmin ⊇ admin ⊇ user.
+var defaultRoleGraph = map[string]roleDef{
+	"superadmin": {Space: SpaceSuperadmins, Includes: []string{"admin"}},
+	"admin":      {Space: SpaceAdmins, Includes: []string{"user"}},
+	"user":       {},
+}
+
+// roleGraph is the active graph; grantedBy[r] lists every role that implies r
+// (including r itself). Both are set once at startup by loadRoleGraph.
+var (
+	roleGraph = defaultRoleGraph
+	grantedBy = mustResolveRoleGraph(defaultRoleGraph)
+)
+
+// loadRoleGraph merges the JSON file named by PF_ROLE_GRAPH into the default
+// graph, so new roles can be added without code changes, e.g.
+//
+//	{"moderator": {"space": "moderators", "includes": ["user"]},
+//	 "admin": {"space": "admins", "includes": ["moderator"]}}
+func loadRoleGraph() error {
+	path := os.Getenv("PF_ROLE_GRAPH")
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read role graph: %w", err)
+	}
+	var extra map[string]roleDef
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return fmt.Errorf("parse role graph: %w", err)
+	}
+
+	merged := map[string]roleDef{}
+	for name, def := range defaultRoleGraph {
+		merged[name] = def
+	}
+	for name, def := range extra {
+		merged[name] = def
+	}
+
+	resolved, err := resolveRoleGraph(merged)
+	if err != nil {
+		return err
+	}
+	roleGraph, grantedBy = merged, resolved
+	return nil
+}
+
+// resolveRoleGraph validates the graph (known includes, no cycles) and
+// returns, for every role, the sorted list of roles that grant it.
+func resolveRoleGraph(g map[string]roleDef) (map[string][]string, error) {
+	for name, def := range g {
+		for _, inc := range def.Includes {
+			if _, ok := g[inc]; !ok {
+				return nil, fmt.Errorf("role %q includes unknown role %q", name, inc)
+			}
+		}
+	}
+
+	out := map[string][]string{}
+	for name := range g {
+		implied, err := impliedRoles(g, name, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		for r := range implied {
+			out[r] = append(out[r], name)
+		}
+	}
+	for r := range out {
+		sort.Strings(out[r])
+	}
+	return out, nil
+}
+
+// mustResolveRoleGraph is resolveRoleGraph for the built-in graph.
+func mustResolveRoleGraph(g map[string]roleDef) map[string][]string {
+	out, err := resolveRoleGraph(g)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// impliedRoles returns role plus everything it includes, transitively.
+// path holds the roles on the current walk and detects cycles.
+func impliedRoles(g map[string]roleDef, role string, path map[string]bool) (map[string]bool, error) {
+	if path[role] {
+		return nil, fmt.Errorf("role graph has a cycle through %q", role)
+	}
+	path[role] = true
+	defer delete(path, role)
+
+	out := map[string]bool{role: true}
+	for _, inc := range g[role].Includes {
+		sub, err := impliedRoles(g, inc, path)
+		if err != nil {
+			return nil, err
+		}
+		for r := range sub {
+			out[r] = true
+		}
+	}
+	return out, nil
+}
+
+// holdsDirectly reports whether the user is a member of the role's own space.
+// c may be nil outside of a request.
+func holdsDirectly(c *gin.Context, userIDStr, role string) bool {
+	def, ok := roleGraph[role]
+	if !ok {
+		return false
+	}
+	if def.Space == "" {
+		return true
+	}
+	return checkPermission(c, userIDStr, def.Space, MemberRole)
+}
+
+// EffectiveRoles returns every role the user holds, directly or by inclusion, sorted.
+func EffectiveRoles(c *gin.Context, userIDStr string) []string {
+	held := map[string]bool{}
+	for role := range roleGraph {
+		if held[role] || !holdsDirectly(c, userIDStr, role) {
+			continue
+		}
+		implied, _ := impliedRoles(roleGraph, role, map[string]bool{})
+		for r := range implied {
+			held[r] = true
+		}
+	}
+
+	out := make([]string, 0, len(held))
+	for r := range held {
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// isRoleSpace reports whether membership of space grants a role in the graph.
+func isRoleSpace(space string) bool {
+	for _, def := range roleGraph {
+		if def.Space != "" && def.Space == space {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+// roles_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveRoleGraph(t *testing.T) {
+	tests := []struct {
+		name    string
+		graph   map[string]roleDef
+		want    map[string][]string
+		wantErr string
+	}{
+		{
+			name:  "default graph",
+			graph: defaultRoleGraph,
+			want: map[string][]string{
+				"superadmin": {"superadmin"},
+				"admin":      {"admin", "superadmin"},
+				"user":       {"admin", "superadmin", "user"},
+			},
+		},
+		{
+			name: "diamond",
+			graph: map[string]roleDef{
+				"top":   {Includes: []string{"left", "right"}},
+				"left":  {Includes: []string{"base"}},
+				"right": {Includes: []string{"base"}},
+				"base":  {},
+			},
+			want: map[string][]string{
+				"top":   {"top"},
+				"left":  {"left", "top"},
+				"right": {"right", "top"},
+				"base":  {"base", "left", "right", "top"},
+			},
+		},
+		{
+			name:    "unknown include",
+			graph:   map[string]roleDef{"admin": {Includes: []string{"ghost"}}},
+			wantErr: `role "admin" includes unknown role "ghost"`,
+		},
+		{
+			name:    "includes itself",
+			graph:   map[string]roleDef{"admin": {Includes: []string{"admin"}}},
+			wantErr: "cycle",
+		},
+		{
+			name: "longer cycle",
+			graph: map[string]roleDef{
+				"a": {Includes: []string{"b"}},
+				"b": {Includes: []string{"c"}},
+				"c": {Includes: []string{"a"}},
+			},
+			wantErr: "cycle",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRoleGraph(tt.graph)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("grantedBy = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRoleGraph(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string // PF_ROLE_GRAPH contents; empty leaves it unset
+		want    map[string][]string
+		wantErr string
+	}{
+		{
+			name: "unset keeps the default graph",
+			want: mustResolveRoleGraph(defaultRoleGraph),
+		},
+		{
+			name: "new role between admin and user",
+			file: `{"moderator": {"space": "moderators", "includes": ["user"]},
+			        "admin": {"space": "admins", "includes": ["moderator"]}}`,
+			want: map[string][]string{
+				"superadmin": {"superadmin"},
+				"admin":      {"admin", "superadmin"},
+				"moderator":  {"admin", "moderator", "superadmin"},
+				"user":       {"admin", "moderator", "superadmin", "user"},
+			},
+		},
+		{
+			name:    "extension with a cycle",
+			file:    `{"user": {"includes": ["superadmin"]}}`,
+			wantErr: "cycle",
+		},
+		{
+			name:    "extension with an unknown include",
+			file:    `{"moderator": {"space": "moderators", "includes": ["helper"]}}`,
+			wantErr: `unknown role "helper"`,
+		},
+		{
+			name:    "malformed file",
+			file:    `{"moderator": `,
+			wantErr: "parse role graph",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prevGraph, prevGrantedBy := roleGraph, grantedBy
+			t.Cleanup(func() { roleGraph, grantedBy = prevGraph, prevGrantedBy })
+
+			t.Setenv("PF_ROLE_GRAPH", "")
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), "roles.json")
+				if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv("PF_ROLE_GRAPH", path)
+			}
+
+			err := loadRoleGraph()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				if !reflect.DeepEqual(grantedBy, prevGrantedBy) {
+					t.Fatalf("a rejected graph replaced the active one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(grantedBy, tt.want) {
+				t.Fatalf("grantedBy = %v, want %v", grantedBy, tt.want)
+			}
+		})
+	}
+}
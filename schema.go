@@ -0,0 +1,269 @@
+// schema.go
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// schemaStatements create the tables owned by this service on top of the
+// base project_forum schema. They run in order on every start, so each
+// statement must be idempotent.
+var schemaStatements = []string{
+	// Server-side sessions backing refresh tokens (14. sessions.go)
+	`CREATE TABLE IF NOT EXISTS auth_sessions (
+		id           BIGSERIAL PRIMARY KEY,
+		user_id      INT NOT NULL,
+		refresh_hash BYTEA NOT NULL UNIQUE,
+		created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+		last_used_at TIMESTAMPTZ,
+		expires_at   TIMESTAMPTZ NOT NULL,
+		revoked_at   TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS auth_sessions_user_idx ON auth_sessions (user_id) WHERE revoked_at IS NULL`,
+
+	// Personal API keys, stored hashed (15. apiKeys.go)
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		id           BIGSERIAL PRIMARY KEY,
+		user_id      INT NOT NULL,
+		name         TEXT NOT NULL,
+		key_prefix   TEXT NOT NULL UNIQUE,
+		key_hash     BYTEA NOT NULL,
+		scopes       TEXT[] NOT NULL,
+		created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+		expires_at   TIMESTAMPTZ,
+		last_used_at TIMESTAMPTZ,
+		revoked_at   TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS api_keys_user_idx ON api_keys (user_id)`,
+
+	// OpenID Connect single sign-on (16. oidcLogin.go)
+	`CREATE TABLE IF NOT EXISTS oidc_login_states (
+		state         TEXT PRIMARY KEY,
+		nonce         TEXT NOT NULL,
+		code_verifier TEXT NOT NULL,
+		created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE TABLE IF NOT EXISTS oidc_identities (
+		issuer     TEXT NOT NULL,
+		subject    TEXT NOT NULL,
+		user_id    INT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		PRIMARY KEY (issuer, subject)
+	)`,
+	// IDs for SSO users without a mapped ID claim, kept clear of roll numbers (oidcUserIDStart)
+	`CREATE SEQUENCE IF NOT EXISTS oidc_user_id_seq START 1000000000`,
+
+	// Audit trail of privileged actions, incl. everything done while impersonating (audit.go)
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id         BIGSERIAL PRIMARY KEY,
+		at         TIMESTAMPTZ NOT NULL DEFAULT now(),
+		actor_id   INT,
+		subject_id INT,
+		action     TEXT NOT NULL,
+		detail     TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor_id, at)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_subject_idx ON audit_log (subject_id, at)`,
+
+	// Token buckets shared between instances (ratelimit.go, PF_RATE_LIMIT_STORE=postgres)
+	`CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets (
+		key        TEXT PRIMARY KEY,
+		tokens     DOUBLE PRECISION NOT NULL,
+		allowed    BOOLEAN NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	)`,
+
+	// One-off data migrations already applied (runOnce)
+	`CREATE TABLE IF NOT EXISTS schema_migrations (
+		name       TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+
+	// Local record of role-space memberships, kept by AssignMemberToSpace /
+	// RemoveMemberFromSpace; the auth library cannot list a space (auth.go).
+	// Older memberships are copied in by backfillRoleGrants.
+	`CREATE TABLE IF NOT EXISTS role_grants (
+		user_id    INT NOT NULL,
+		space      TEXT NOT NULL,
+		granted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		PRIMARY KEY (space, user_id)
+	)`,
+	// Time-limited grants, revoked by the sweeper in roleexpiry.go
+	`ALTER TABLE role_grants ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ`,
+	`CREATE INDEX IF NOT EXISTS role_grants_expires_idx ON role_grants (expires_at) WHERE expires_at IS NOT NULL`,
+
+	// Role changes waiting for a second superadmin (20. roleChangeRequests.go)
+	`CREATE TABLE IF NOT EXISTS role_change_requests (
+		id           BIGSERIAL PRIMARY KEY,
+		action       TEXT NOT NULL,
+		user_id      INT NOT NULL,
+		space        TEXT NOT NULL,
+		requested_by INT NOT NULL,
+		requested_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		expires_at   TIMESTAMPTZ NOT NULL,
+		status       TEXT NOT NULL DEFAULT 'pending',
+		decided_by   INT,
+		decided_at   TIMESTAMPTZ
+	)`,
+	// Two-person grants carry what AssignMemberToSpaceUntil needs
+	`ALTER TABLE role_change_requests ADD COLUMN IF NOT EXISTS user_name TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE role_change_requests ADD COLUMN IF NOT EXISTS grant_expires_at TIMESTAMPTZ`,
+
+	// Account suspensions; lifted ones are kept as history (22. suspensions.go)
+	`CREATE TABLE IF NOT EXISTS user_suspensions (
+		id           BIGSERIAL PRIMARY KEY,
+		user_id      INT NOT NULL,
+		reason       TEXT NOT NULL,
+		suspended_by INT NOT NULL,
+		suspended_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		until        TIMESTAMPTZ,
+		lifted_by    INT,
+		lifted_at    TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS user_suspensions_user_idx ON user_suspensions (user_id) WHERE lifted_at IS NULL`,
+
+	// Project search: weighted full-text vectors and trigram name indexes (24. searchProjects.go)
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`ALTER TABLE approved_projects ADD COLUMN IF NOT EXISTS search_tsv tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+		setweight(to_tsvector('english', COALESCE(description, '')), 'B')) STORED`,
+	`CREATE INDEX IF NOT EXISTS approved_projects_search_idx ON approved_projects USING GIN (search_tsv)`,
+	`CREATE INDEX IF NOT EXISTS approved_projects_name_trgm_idx ON approved_projects USING GIN (name gin_trgm_ops)`,
+	`ALTER TABLE buffer_projects ADD COLUMN IF NOT EXISTS search_tsv tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+		setweight(to_tsvector('english', COALESCE(description, '')), 'B')) STORED`,
+	`CREATE INDEX IF NOT EXISTS buffer_projects_search_idx ON buffer_projects USING GIN (search_tsv)`,
+	`CREATE INDEX IF NOT EXISTS buffer_projects_name_trgm_idx ON buffer_projects USING GIN (name gin_trgm_ops)`,
+	`ALTER TABLE deleted_projects ADD COLUMN IF NOT EXISTS search_tsv tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+		setweight(to_tsvector('english', COALESCE(description, '')), 'B')) STORED`,
+	`CREATE INDEX IF NOT EXISTS deleted_projects_search_idx ON deleted_projects USING GIN (search_tsv)`,
+	`CREATE INDEX IF NOT EXISTS deleted_projects_name_trgm_idx ON deleted_projects USING GIN (name gin_trgm_ops)`,
+
+	// Project edits waiting for an admin (25. editProject.go, PF_PROJECT_EDIT_REVIEW=1)
+	`CREATE TABLE IF NOT EXISTS project_edit_requests (
+		id           BIGSERIAL PRIMARY KEY,
+		p_id         INT NOT NULL,
+		changes      JSONB NOT NULL,
+		note         TEXT NOT NULL DEFAULT '',
+		requested_by INT NOT NULL,
+		requested_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		status       TEXT NOT NULL DEFAULT 'pending',
+		decided_by   INT,
+		decided_at   TIMESTAMPTZ
+	)`,
+
+	// Edit history; no foreign key so it outlives the project (26. projectRevisions.go)
+	`CREATE TABLE IF NOT EXISTS project_revisions (
+		p_id       INT NOT NULL,
+		rev        INT NOT NULL,
+		fields     JSONB NOT NULL,
+		author_id  INT,
+		note       TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		PRIMARY KEY (p_id, rev)
+	)`,
+	// Whether an admin made or approved the revision; unreviewed edits add up
+	// against PF_PROJECT_EDIT_TRIVIAL_CHARS (25. editProject.go)
+	`ALTER TABLE project_revisions ADD COLUMN IF NOT EXISTS reviewed BOOLEAN NOT NULL DEFAULT true`,
+
+	// Admin-curated categories, free-form tags and tech stack (27. taxonomy.go)
+	`CREATE TABLE IF NOT EXISTS categories (
+		slug       TEXT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`ALTER TABLE buffer_projects ADD COLUMN IF NOT EXISTS category TEXT REFERENCES categories (slug) ON UPDATE CASCADE ON DELETE SET NULL`,
+	`ALTER TABLE buffer_projects ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE buffer_projects ADD COLUMN IF NOT EXISTS tech_stack TEXT[] NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE approved_projects ADD COLUMN IF NOT EXISTS category TEXT REFERENCES categories (slug) ON UPDATE CASCADE ON DELETE SET NULL`,
+	`ALTER TABLE approved_projects ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE approved_projects ADD COLUMN IF NOT EXISTS tech_stack TEXT[] NOT NULL DEFAULT '{}'`,
+	`CREATE INDEX IF NOT EXISTS approved_projects_tags_idx ON approved_projects USING GIN (tags)`,
+	`CREATE INDEX IF NOT EXISTS approved_projects_tech_stack_idx ON approved_projects USING GIN (tech_stack)`,
+	// The archive keeps them as plain values, without the foreign key
+	`ALTER TABLE deleted_projects ADD COLUMN IF NOT EXISTS category TEXT`,
+	`ALTER TABLE deleted_projects ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE deleted_projects ADD COLUMN IF NOT EXISTS tech_stack TEXT[] NOT NULL DEFAULT '{}'`,
+
+	// Project lifecycle and its history (12. projectStatusUpdate.go).
+	// NOT VALID: rows from before the lifecycle are left alone.
+	`DO $$ BEGIN
+		ALTER TABLE approved_projects ADD CONSTRAINT approved_projects_status_check
+			CHECK (status IN ('upcoming', 'in_progress', 'completed', 'on_hold', 'cancelled')) NOT VALID;
+	EXCEPTION WHEN duplicate_object THEN NULL;
+	END $$`,
+	`CREATE TABLE IF NOT EXISTS project_status_history (
+		id          BIGSERIAL PRIMARY KEY,
+		p_id        INT NOT NULL, -- no FK: kept after the project is deleted
+		from_status TEXT,
+		to_status   TEXT NOT NULL,
+		changed_by  INT,
+		reason      TEXT NOT NULL DEFAULT '',
+		changed_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE INDEX IF NOT EXISTS project_status_history_p_id_idx ON project_status_history (p_id, changed_at)`,
+
+	// Planned dates and the scheduler acting on them (projectschedule.go)
+	`ALTER TABLE buffer_projects ADD COLUMN IF NOT EXISTS planned_start DATE`,
+	`ALTER TABLE buffer_projects ADD COLUMN IF NOT EXISTS planned_end DATE`,
+	`ALTER TABLE approved_projects ADD COLUMN IF NOT EXISTS end_date DATE`,
+	`ALTER TABLE approved_projects ADD COLUMN IF NOT EXISTS overdue_since DATE`,
+	`CREATE INDEX IF NOT EXISTS approved_projects_upcoming_idx ON approved_projects (start_date) WHERE status = 'upcoming'`,
+	`CREATE INDEX IF NOT EXISTS approved_projects_end_date_idx ON approved_projects (end_date) WHERE end_date IS NOT NULL`,
+
+	// Full snapshots in the archive, for restoring (5. viewDeletedProjects.go).
+	// Older archive rows have no status, dates or team.
+	`ALTER TABLE deleted_projects ADD COLUMN IF NOT EXISTS status TEXT`,
+	`ALTER TABLE deleted_projects ADD COLUMN IF NOT EXISTS start_date DATE`,
+	`ALTER TABLE deleted_projects ADD COLUMN IF NOT EXISTS end_date DATE`,
+	`ALTER TABLE deleted_projects ADD COLUMN IF NOT EXISTS maintainers JSONB NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE deleted_projects ADD COLUMN IF NOT EXISTS contributors JSONB NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE deleted_projects ADD COLUMN IF NOT EXISTS deleted_by INT`,
+
+	// Retention, legal holds and purge reports (retention.go)
+	`ALTER TABLE buffer_projects ADD COLUMN IF NOT EXISTS rejected_at TIMESTAMPTZ`,
+	`ALTER TABLE buffer_projects ADD COLUMN IF NOT EXISTS legal_hold BOOLEAN NOT NULL DEFAULT false`,
+	`ALTER TABLE deleted_projects ADD COLUMN IF NOT EXISTS legal_hold BOOLEAN NOT NULL DEFAULT false`,
+	`CREATE TABLE IF NOT EXISTS purge_runs (
+		id          BIGSERIAL PRIMARY KEY,
+		table_name  TEXT NOT NULL,
+		cutoff      TIMESTAMPTZ NOT NULL,
+		purged      INT NOT NULL,
+		purged_ids  INT[] NOT NULL,
+		batches     INT NOT NULL,
+		started_at  TIMESTAMPTZ NOT NULL,
+		finished_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		error       TEXT
+	)`,
+}
+
+// runOnce runs fn unless a migration called name has already been applied
+// to this database, and then marks it applied. fn must be idempotent: two
+// instances starting together may both run it.
+func runOnce(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	var done bool
+	if err := conn.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE name=$1)`, name).Scan(&done); err != nil {
+		return err
+	}
+	if done {
+		return nil
+	}
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	_, err := conn.Exec(ctx, `INSERT INTO schema_migrations (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, name)
+	return err
+}
+
+// ensureSchema applies schemaStatements to the project_forum DB.
+func ensureSchema(ctx context.Context) error {
+	for _, stmt := range schemaStatements {
+		if _, err := conn.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("schema statement failed: %w", err)
+		}
+	}
+	return nil
+}